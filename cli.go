@@ -1,5 +1,12 @@
 package main
 
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+)
+
 const (
 	Succeeded = 0 + iota
 	InvalidOption
@@ -8,6 +15,15 @@ const (
 	FailedExecute
 )
 
+// TimedOut is the exit status when a command timed out, the same as timeout(1).
+const TimedOut = 124
+
+type exitCoder interface {
+	ExitCode() int
+}
+
+var ChangeDir = os.Chdir
+
 type CLI interface {
 	Run([]string) int
 }
@@ -25,25 +41,121 @@ func (c *CLIImpl) Run(args []string) int {
 		return InvalidOption
 	}
 
-	buf, err := ReadConfig(option.ConfigPath())
+	SetLogLevel(option.LogLevel())
+	Debug("Specified tasks: %v, task args: %v", option.SpecifiedTasks(), option.TaskArgs())
+
+	if dir := option.WorkingDir(); dir != "" {
+		if err := ChangeDir(dir); err != nil {
+			Error("Failed to change directory. dir: %s", dir)
+			return InvalidOption
+		}
+	}
+
+	path, err := ResolveConfigPath(option.ConfigPath())
+	if err != nil {
+		return UnreadConfig
+	}
+	Debug("Config file: %s", path)
+
+	buf, err := ReadConfig(path)
 	if err != nil {
 		return UnreadConfig
 	}
 
-	config, err := ParseConfig(buf)
+	if option.WillLint() {
+		return lint(path, buf, option)
+	}
+
+	config, err := ParseConfig(path, buf)
 	if err != nil {
 		return InvalidConfig
 	}
 
+	if isLintCommand(option, config) {
+		return lint(path, buf, option)
+	}
+
 	if option.WillBeShowTasks() {
-		config.ShowAllDefinedTasks()
+		filter, err := taskFilter(option)
+		if err != nil {
+			return InvalidOption
+		}
+		config.ShowAllDefinedTasks(filter, option.ShowsHiddenTasks(), option.UsesJSON())
+		return Succeeded
+	}
+
+	if name, ok := helpTaskName(option, config); ok {
+		if err := config.ShowTaskHelp(name); err != nil {
+			return InvalidOption
+		}
 		return Succeeded
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopHandlingSignals := HandleSignals(cancel)
+	defer stopHandlingSignals()
+
 	runner := NewRunner(option, config)
-	if err := runner.Run(); err != nil {
+	if err := runner.Run(ctx); err != nil {
+		if exitErr, ok := err.(exitCoder); ok && !option.UsesFixedExitCode() {
+			return exitErr.ExitCode()
+		}
 		return FailedExecute
 	}
 
 	return Succeeded
 }
+
+// taskFilter returns the regexp to filter tasks given as `taskal -T PATTERN ...`,
+// which matches any of the patterns.
+func taskFilter(option Option) (*regexp.Regexp, error) {
+	patterns := option.SpecifiedTasks()
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	filter, err := regexp.Compile(strings.Join(patterns, "|"))
+	if err != nil {
+		Error("Invalid task filter. filter: %s", strings.Join(patterns, " "))
+		return nil, err
+	}
+	return filter, nil
+}
+
+// helpTaskName returns the task name given as `taskal help TASK`,
+// unless a task named help is defined.
+func helpTaskName(option Option, config Config) (string, bool) {
+	tasks := option.SpecifiedTasks()
+	if len(tasks) != 2 || tasks[0] != "help" {
+		return "", false
+	}
+	for _, task := range config.DefinedTasks() {
+		if task.Name() == "help" {
+			return "", false
+		}
+	}
+	return tasks[1], true
+}
+
+// isLintCommand reports whether `taskal lint` is given, unless a task named lint is defined
+// or lint is a filter of -T.
+func isLintCommand(option Option, config Config) bool {
+	tasks := option.SpecifiedTasks()
+	if len(tasks) != 1 || tasks[0] != "lint" || option.WillBeShowTasks() {
+		return false
+	}
+	for _, task := range config.DefinedTasks() {
+		if task.Name() == "lint" {
+			return false
+		}
+	}
+	return true
+}
+
+func lint(path string, buf string, option Option) int {
+	if ShowLintIssues(LintConfig(path, buf), option.UsesJSON()) {
+		return InvalidConfig
+	}
+	return Succeeded
+}
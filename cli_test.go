@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
 	"testing"
 )
 
@@ -23,11 +25,15 @@ func TestCLIImpl_Run(t *testing.T) {
 	originParseOptionFunc := ParseOption
 	originReadConfigFunc := ReadConfig
 	originParseConfigFunc := ParseConfig
+	originChangeDirFunc := ChangeDir
+	originLintConfigFunc := LintConfig
 
 	var restoreOriginFunc = func() {
+		ChangeDir = originChangeDirFunc
 		ParseOption = originParseOptionFunc
 		ReadConfig = originReadConfigFunc
 		ParseConfig = originParseConfigFunc
+		LintConfig = originLintConfigFunc
 	}
 
 	defer restoreOriginFunc()
@@ -43,11 +49,34 @@ func TestCLIImpl_Run(t *testing.T) {
 		assert.Equal(expected, actual)
 	})
 
+	t.Run("When changing the working directory fails.", func(t *testing.T) {
+		assert := assert2.New(t)
+		ParseOption = func(args []string) (Option, error) {
+			option := new(MockOption)
+			option.On("WorkingDir").Return("./notexists")
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
+			return option, nil
+		}
+		ChangeDir = func(dir string) error {
+			return fmt.Errorf("no such file or directory")
+		}
+
+		actual := target.Run(args)
+		expected := InvalidOption
+		assert.Equal(expected, actual)
+	})
+
 	t.Run("When reading config file failed.", func(t *testing.T) {
 		assert := assert2.New(t)
 		ParseOption = func(args []string) (Option, error) {
 			option := new(MockOption)
-			option.On("ConfigPath").Return("")
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
 			return option, nil
 		}
 		ReadConfig = func(path string) (string, error) {
@@ -63,13 +92,18 @@ func TestCLIImpl_Run(t *testing.T) {
 		assert := assert2.New(t)
 		ParseOption = func(args []string) (Option, error) {
 			option := new(MockOption)
-			option.On("ConfigPath").Return("")
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
 			return option, nil
 		}
 		ReadConfig = func(path string) (string, error) {
 			return "", nil
 		}
-		ParseConfig = func(buf string) (Config, error) {
+		ParseConfig = func(path string, buf string) (Config, error) {
 			return nil, fmt.Errorf("invalid config")
 		}
 
@@ -78,46 +112,186 @@ func TestCLIImpl_Run(t *testing.T) {
 		assert.Equal(expected, actual)
 	})
 
+	t.Run("When lint was specified.", func(t *testing.T) {
+		ParseOption = func(args []string) (Option, error) {
+			option := new(MockOption)
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(true)
+			option.On("UsesJSON").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
+			return option, nil
+		}
+		ReadConfig = func(path string) (string, error) {
+			return "", nil
+		}
+
+		t.Run("And errors are found.", func(t *testing.T) {
+			assert := assert2.New(t)
+			LintConfig = func(path string, buf string) []*LintIssue {
+				return []*LintIssue{{Path: path, Severity: LintError, Rule: "config", Message: "invalid"}}
+			}
+
+			actual := target.Run(args)
+			expected := InvalidConfig
+			assert.Equal(expected, actual)
+		})
+
+		t.Run("And only warnings are found.", func(t *testing.T) {
+			assert := assert2.New(t)
+			LintConfig = func(path string, buf string) []*LintIssue {
+				return []*LintIssue{{Path: path, Severity: LintWarning, Rule: "empty-task", Message: "empty"}}
+			}
+
+			actual := target.Run(args)
+			expected := Succeeded
+			assert.Equal(expected, actual)
+		})
+	})
+
 	t.Run("When will be show tasks was specified.", func(t *testing.T) {
+		ReadConfig = func(path string) (string, error) {
+			return "", nil
+		}
+
+		t.Run("And no filter is given.", func(t *testing.T) {
+			assert := assert2.New(t)
+			ParseOption = func(args []string) (Option, error) {
+				option := new(MockOption)
+				option.On("WorkingDir").Return("")
+				option.On("ConfigPath").Return("taskal.yml")
+				option.On("WillLint").Return(false)
+				option.On("LogLevel").Return(LogLevelDebug)
+				option.On("SpecifiedTasks").Return()
+				option.On("TaskArgs").Return()
+				option.On("WillBeShowTasks").Return(true)
+				option.On("ShowsHiddenTasks").Return(true)
+				option.On("UsesJSON").Return(true)
+				return option, nil
+			}
+			var config *MockConfig
+			ParseConfig = func(path string, buf string) (Config, error) {
+				config = new(MockConfig)
+				config.On("ShowAllDefinedTasks", (*regexp.Regexp)(nil), true, true)
+				return config, nil
+			}
+
+			actual := target.Run(args)
+			expected := Succeeded
+			assert.Equal(expected, actual)
+			config.AssertExpectations(t)
+		})
+
+		t.Run("And filters are given.", func(t *testing.T) {
+			assert := assert2.New(t)
+			ParseOption = func(args []string) (Option, error) {
+				option := new(MockOption)
+				option.On("WorkingDir").Return("")
+				option.On("ConfigPath").Return("taskal.yml")
+				option.On("WillLint").Return(false)
+				option.On("LogLevel").Return(LogLevelDebug)
+				option.On("SpecifiedTasks").Return("^go:", "lint[0-9]$")
+				option.On("TaskArgs").Return()
+				option.On("WillBeShowTasks").Return(true)
+				option.On("ShowsHiddenTasks").Return(false)
+				option.On("UsesJSON").Return(false)
+				return option, nil
+			}
+			var filter *regexp.Regexp
+			ParseConfig = func(path string, buf string) (Config, error) {
+				config := new(MockConfig)
+				config.On("ShowAllDefinedTasks", mock.Anything, false, false).Run(func(args mock.Arguments) {
+					filter = args.Get(0).(*regexp.Regexp)
+				})
+				return config, nil
+			}
+
+			actual := target.Run(args)
+			expected := Succeeded
+			assert.Equal(expected, actual)
+			expected2 := "^go:|lint[0-9]$"
+			assert.Equal(expected2, filter.String())
+		})
+
+		t.Run("And an invalid filter is given.", func(t *testing.T) {
+			assert := assert2.New(t)
+			ParseOption = func(args []string) (Option, error) {
+				option := new(MockOption)
+				option.On("WorkingDir").Return("")
+				option.On("ConfigPath").Return("taskal.yml")
+				option.On("WillLint").Return(false)
+				option.On("LogLevel").Return(LogLevelDebug)
+				option.On("SpecifiedTasks").Return("go:(")
+				option.On("TaskArgs").Return()
+				option.On("WillBeShowTasks").Return(true)
+				return option, nil
+			}
+			ParseConfig = func(path string, buf string) (Config, error) {
+				return new(MockConfig), nil
+			}
+
+			actual := target.Run(args)
+			expected := InvalidOption
+			assert.Equal(expected, actual)
+		})
+	})
+
+	t.Run("When help of a task is specified.", func(t *testing.T) {
 		assert := assert2.New(t)
 		ParseOption = func(args []string) (Option, error) {
 			option := new(MockOption)
-			option.On("ConfigPath").Return("")
-			option.On("WillBeShowTasks").Return(true)
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return("help", "deploy")
+			option.On("TaskArgs").Return()
+			option.On("WillBeShowTasks").Return(false)
 			return option, nil
 		}
 		ReadConfig = func(path string) (string, error) {
 			return "", nil
 		}
-		ParseConfig = func(buf string) (Config, error) {
-			config := new(MockConfig)
-			config.On("ShowAllDefinedTasks")
+		var config *MockConfig
+		ParseConfig = func(path string, buf string) (Config, error) {
+			config = new(MockConfig)
+			config.On("DefinedTasks").Return(&DefinedTaskImpl{name: "deploy"})
+			config.On("ShowTaskHelp", "deploy").Return(nil)
 			return config, nil
 		}
 
 		actual := target.Run(args)
 		expected := Succeeded
 		assert.Equal(expected, actual)
+
+		config.AssertCalled(t, "ShowTaskHelp", "deploy")
 	})
 
 	t.Run("When the failed to run Runner.", func(t *testing.T) {
 		assert := assert2.New(t)
 		ParseOption = func(args []string) (Option, error) {
 			option := new(MockOption)
-			option.On("ConfigPath").Return("")
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
 			option.On("WillBeShowTasks").Return(false)
 			return option, nil
 		}
 		ReadConfig = func(path string) (string, error) {
 			return "", nil
 		}
-		ParseConfig = func(buf string) (Config, error) {
+		ParseConfig = func(path string, buf string) (Config, error) {
 			config := new(MockConfig)
 			return config, nil
 		}
 		NewRunner = func(option Option, config Config) Runner {
 			runner := new(MockRunner)
-			runner.On("Run").Return(fmt.Errorf("failed to run"))
+			runner.On("Run", mock.Anything).Return(fmt.Errorf("failed to run"))
 			return runner
 		}
 
@@ -126,24 +300,109 @@ func TestCLIImpl_Run(t *testing.T) {
 		assert.Equal(expected, actual)
 	})
 
+	t.Run("When the command failed in Runner.", func(t *testing.T) {
+		ReadConfig = func(path string) (string, error) {
+			return "", nil
+		}
+		ParseConfig = func(path string, buf string) (Config, error) {
+			config := new(MockConfig)
+			return config, nil
+		}
+		NewRunner = func(option Option, config Config) Runner {
+			runner := new(MockRunner)
+			runner.On("Run", mock.Anything).Return(&ExitError{Code: 2, err: fmt.Errorf("exit status 2")})
+			return runner
+		}
+
+		t.Run("And fixed exit code is not used.", func(t *testing.T) {
+			assert := assert2.New(t)
+			ParseOption = func(args []string) (Option, error) {
+				option := new(MockOption)
+				option.On("WorkingDir").Return("")
+				option.On("ConfigPath").Return("taskal.yml")
+				option.On("WillLint").Return(false)
+				option.On("LogLevel").Return(LogLevelDebug)
+				option.On("SpecifiedTasks").Return()
+				option.On("TaskArgs").Return()
+				option.On("WillBeShowTasks").Return(false)
+				option.On("UsesFixedExitCode").Return(false)
+				return option, nil
+			}
+
+			actual := target.Run(args)
+			expected := 2
+			assert.Equal(expected, actual)
+		})
+
+		t.Run("And fixed exit code is used.", func(t *testing.T) {
+			assert := assert2.New(t)
+			ParseOption = func(args []string) (Option, error) {
+				option := new(MockOption)
+				option.On("WorkingDir").Return("")
+				option.On("ConfigPath").Return("taskal.yml")
+				option.On("WillLint").Return(false)
+				option.On("LogLevel").Return(LogLevelDebug)
+				option.On("SpecifiedTasks").Return()
+				option.On("TaskArgs").Return()
+				option.On("WillBeShowTasks").Return(false)
+				option.On("UsesFixedExitCode").Return(true)
+				return option, nil
+			}
+
+			actual := target.Run(args)
+			expected := FailedExecute
+			assert.Equal(expected, actual)
+		})
+	})
+
+	t.Run("When the command timed out in Runner.", func(t *testing.T) {
+		assert := assert2.New(t)
+		ParseOption = func(args []string) (Option, error) {
+			option := new(MockOption)
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
+			option.On("WillBeShowTasks").Return(false)
+			option.On("UsesFixedExitCode").Return(false)
+			return option, nil
+		}
+		NewRunner = func(option Option, config Config) Runner {
+			runner := new(MockRunner)
+			runner.On("Run", mock.Anything).Return(&TimeoutError{Command: "sleep 10"})
+			return runner
+		}
+
+		actual := target.Run(args)
+		expected := TimedOut
+		assert.Equal(expected, actual)
+	})
+
 	t.Run("When the succeeded to run Runner.", func(t *testing.T) {
 		assert := assert2.New(t)
 		ParseOption = func(args []string) (Option, error) {
 			option := new(MockOption)
-			option.On("ConfigPath").Return("")
+			option.On("WorkingDir").Return("")
+			option.On("ConfigPath").Return("taskal.yml")
+			option.On("WillLint").Return(false)
+			option.On("LogLevel").Return(LogLevelDebug)
+			option.On("SpecifiedTasks").Return()
+			option.On("TaskArgs").Return()
 			option.On("WillBeShowTasks").Return(false)
 			return option, nil
 		}
 		ReadConfig = func(path string) (string, error) {
 			return "", nil
 		}
-		ParseConfig = func(buf string) (Config, error) {
+		ParseConfig = func(path string, buf string) (Config, error) {
 			config := new(MockConfig)
 			return config, nil
 		}
 		NewRunner = func(option Option, config Config) Runner {
 			runner := new(MockRunner)
-			runner.On("Run").Return(nil)
+			runner.On("Run", mock.Anything).Return(nil)
 			return runner
 		}
 
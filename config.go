@@ -1,25 +1,43 @@
 package main
 
 import (
-	"gopkg.in/yaml.v2"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 type Config interface {
+	Path() string
 	AddDefinedTask(DefinedTask)
 	DefinedTasks() []DefinedTask
-	ShowAllDefinedTasks()
+	ShowAllDefinedTasks(*regexp.Regexp, bool, bool)
+	ShowTaskHelp(string) error
 }
 
 type ConfigImpl struct {
 	path         string
 	definedTasks []DefinedTask
+	// duplicates holds the tasks skipped while parsing because their names collide with other tasks.
+	duplicates DuplicateTaskErrors
 }
 
-type Node interface{}
-type Document map[string]Node
+// ConfigFileNames are the names of config files searched by FindConfig, in order of priority.
+var ConfigFileNames = []string{
+	"taskal.yml",
+	"taskal.yaml",
+	".taskal.yml",
+}
+
+func (c *ConfigImpl) Path() string {
+	return c.path
+}
 
 func (c *ConfigImpl) AddDefinedTask(task DefinedTask) {
 	c.definedTasks = append(c.definedTasks, task)
@@ -29,11 +47,192 @@ func (c *ConfigImpl) DefinedTasks() []DefinedTask {
 	return c.definedTasks
 }
 
-func (c *ConfigImpl) ShowAllDefinedTasks() {
-	Printf("All defined tasks:\n")
+// ShowAllDefinedTasks shows the tasks whose names match the filter, or all tasks if the filter is nil.
+// The tasks are grouped into a tree by their namespaces, with aligned descriptions.
+// Hidden tasks are shown only if showsHidden is true.
+func (c *ConfigImpl) ShowAllDefinedTasks(filter *regexp.Regexp, showsHidden bool, usesJSON bool) {
+	var tasks []DefinedTask
 	for _, task := range c.DefinedTasks() {
-		Printf("%s", task.Name())
+		if task.Hidden() && !showsHidden {
+			continue
+		}
+		if filter == nil || filter.MatchString(task.Name()) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	if usesJSON {
+		showTasksInJSON(tasks)
+		return
+	}
+
+	Printf("All defined tasks:\n")
+	root := newTaskTree(tasks)
+	root.show("", root.width(""))
+}
+
+// taskListItem is a task in the JSON output of -T.
+type taskListItem struct {
+	Name        string          `json:"name"`
+	Aliases     []string        `json:"aliases"`
+	Description string          `json:"description"`
+	Params      []paramListItem `json:"params"`
+	Deps        []string        `json:"deps"`
+	Tags        []string        `json:"tags"`
+	Hidden      bool            `json:"hidden"`
+	Path        string          `json:"path"`
+	Line        int             `json:"line"`
+}
+
+type paramListItem struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Default     *string  `json:"default"`
+	Required    bool     `json:"required"`
+	Enum        []string `json:"enum"`
+}
+
+func showTasksInJSON(tasks []DefinedTask) {
+	items := []taskListItem{}
+	for _, task := range tasks {
+		item := taskListItem{
+			Name:        task.Name(),
+			Aliases:     append([]string{}, task.Aliases()...),
+			Description: task.Description(),
+			Params:      []paramListItem{},
+			Deps:        append([]string{}, task.Dependencies()...),
+			Tags:        append([]string{}, task.Tags()...),
+			Hidden:      task.Hidden(),
+			Path:        task.SourcePath(),
+			Line:        task.SourceLine(),
+		}
+		for _, param := range task.Params() {
+			paramItem := paramListItem{
+				Name:        param.Name,
+				Description: param.Description,
+				Required:    param.Required,
+				Enum:        append([]string{}, param.Enum...),
+			}
+			if param.HasDefault {
+				value := param.Default
+				paramItem.Default = &value
+			}
+			item.Params = append(item.Params, paramItem)
+		}
+		items = append(items, item)
+	}
+
+	buf, _ := json.MarshalIndent(items, "", "  ")
+	Printf("%s", buf)
+}
+
+// taskTree is a namespace or a task in the output of -T.
+// A node can be both, when a task has the same name as a namespace.
+type taskTree struct {
+	name     string
+	task     DefinedTask
+	children []*taskTree
+}
+
+func newTaskTree(tasks []DefinedTask) *taskTree {
+	root := &taskTree{}
+	for _, task := range tasks {
+		node := root
+		for _, name := range strings.Split(task.Name(), ":") {
+			node = node.child(name)
+		}
+		node.task = task
 	}
+	return root
+}
+
+func (t *taskTree) child(name string) *taskTree {
+	for _, child := range t.children {
+		if child.name == name {
+			return child
+		}
+	}
+	child := &taskTree{name: name}
+	t.children = append(t.children, child)
+	return child
+}
+
+// width returns the width of the name column, which is the longest indented name of the tasks.
+func (t *taskTree) width(indent string) int {
+	width := 0
+	for _, child := range t.children {
+		if child.task != nil && len(indent)+len(child.name) > width {
+			width = len(indent) + len(child.name)
+		}
+		if w := child.width(indent + "  "); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+func (t *taskTree) show(indent string, width int) {
+	for _, child := range t.children {
+		if child.task == nil {
+			Printf("%s%s:", indent, child.name)
+		} else {
+			name := indent + child.name
+			description := strings.SplitN(child.task.Description(), "\n", 2)[0]
+			if aliases := child.task.Aliases(); len(aliases) > 0 {
+				description = strings.TrimSpace(fmt.Sprintf("%s (aliases: %s)", description, strings.Join(aliases, ", ")))
+			}
+			if tags := child.task.Tags(); len(tags) > 0 {
+				description = strings.TrimSpace(fmt.Sprintf("%s (tags: %s)", description, strings.Join(tags, ", ")))
+			}
+			if description != "" {
+				Printf("%-*s  %s", width, name, description)
+			} else {
+				Printf("%s", name)
+			}
+			for _, param := range child.task.Params() {
+				Printf("%s  %s", indent, param.Usage())
+			}
+		}
+		child.show(indent+"  ", width)
+	}
+}
+
+// ShowTaskHelp shows the description, the dependencies and the parameters of the task.
+func (c *ConfigImpl) ShowTaskHelp(name string) error {
+	task, found := findTaskByName(c.DefinedTasks(), name)
+	if !found {
+		Error("Task is not defined. task: %s", name)
+		return fmt.Errorf("task is not defined")
+	}
+
+	Printf("Task: %s", task.Name())
+	if task.Description() != "" {
+		Printf("  %s", task.Description())
+	}
+	if len(task.Aliases()) > 0 {
+		Printf("")
+		Printf("Aliases: %s", strings.Join(task.Aliases(), ", "))
+	}
+	if len(task.Dependencies()) > 0 {
+		Printf("")
+		Printf("Dependencies: %s", strings.Join(task.Dependencies(), ", "))
+	}
+
+	usage := []string{"taskal", task.Name()}
+	if len(task.Params()) > 0 {
+		Printf("")
+		Printf("Parameters:")
+		for _, param := range task.Params() {
+			Printf("  %s", param.Usage())
+			if param.Required {
+				usage = append(usage, param.Name+"=<value>")
+			}
+		}
+	}
+	Printf("")
+	Printf("Usage:")
+	Printf("  %s", strings.Join(usage, " "))
+	return nil
 }
 
 func (c *ConfigImpl) sortDefinedTasks() {
@@ -42,6 +241,43 @@ func (c *ConfigImpl) sortDefinedTasks() {
 	})
 }
 
+// ResolveConfigPath returns the config file path given by the option,
+// the TASKAL_CONFIG environment variable, or found by FindConfig, in this order.
+var ResolveConfigPath = func(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	if env := os.Getenv("TASKAL_CONFIG"); env != "" {
+		return env, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return FindConfig(dir)
+}
+
+// FindConfig searches the config file in the directory and its parent directories.
+func FindConfig(dir string) (string, error) {
+	for {
+		for _, name := range ConfigFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			Error("Config file not found. names: %s", strings.Join(ConfigFileNames, ", "))
+			return "", fmt.Errorf("config file not found")
+		}
+		dir = parent
+	}
+}
+
 var ReadConfig = func(path string) (string, error) {
 	if buf, err := ioutil.ReadFile(path); err != nil {
 		Error("Config file read error. path: %s", path)
@@ -51,43 +287,644 @@ var ReadConfig = func(path string) (string, error) {
 	}
 }
 
-var ParseConfig = func(buf string) (Config, error) {
-	config := &ConfigImpl{}
+var ParseConfig = func(path string, buf string) (Config, error) {
+	config := &ConfigImpl{
+		path: path,
+	}
 
-	var document = make(Document)
-	if err := yaml.Unmarshal([]byte(buf), &document); err != nil {
-		Error(err.Error())
+	file := &configFile{config: config, path: path}
+	err := file.parse(buf)
+	for _, duplicate := range config.duplicates {
+		Error(duplicate.Error())
+	}
+	if err != nil {
+		if configErrors, ok := err.(ConfigErrors); ok {
+			for _, configErr := range configErrors {
+				Error(configErr.Error())
+			}
+		} else {
+			Error(err.Error())
+		}
 		return nil, err
 	}
+	if len(config.duplicates) > 0 {
+		return nil, config.duplicates
+	}
+
+	config.sortDefinedTasks()
+
+	return config, nil
+}
+
+// configFile is a config file being parsed, which is the root config file or an included file.
+type configFile struct {
+	config    *ConfigImpl
+	path      string
+	namespace string
+	env       []string
+	vars      []Var
+	parent    *configFile
+	// included holds the files already included with their namespaces, which are shared by all files of the config.
+	included map[string]bool
+}
+
+func (f *configFile) parse(buf string) error {
+	document, errs := unmarshalConfig(f.path, buf)
+	if errs != nil {
+		return errs
+	}
+	validator := validateConfig(f.path, document)
+	if len(validator.errors) > 0 {
+		return validator.errors
+	}
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	rootDir, err := filepath.Abs(filepath.Dir(f.path))
+	if err != nil {
+		return err
+	}
 
-	var task DefinedTask
-	for taskName, rootNode := range document {
-		if strings.HasPrefix(taskName, "_") {
+	keys, values := mapItems(resolve(document.Content[0]))
+	// The variables of dotenv files are overridden by env regardless of the order of the keys.
+	for i, key := range keys {
+		if key.Value == "dotenv" && isSetting(key.Value, values[i]) {
+			if err := f.parseDotenv(values[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for i, key := range keys {
+		if !isSetting(key.Value, values[i]) {
+			continue
+		}
+		switch key.Value {
+		case "env":
+			if err := f.parseEnv(values[i]); err != nil {
+				return err
+			}
+		case "vars":
+			if err := f.parseVars(values[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	var includes []*yaml.Node
+	for i, key := range keys {
+		taskName := key.Value
+		if isSetting(taskName, values[i]) {
+			if taskName == "include" || taskName == "_include" {
+				includes = append(includes, values[i])
+			}
+			continue
+		}
+		hidden := strings.HasPrefix(taskName, "_")
+		if hidden && !validator.hiddenTasks[taskName] {
 			continue
 		}
 
-		if command, ok := rootNode.(string); ok {
-			task = NewDefinedTask(taskName)
-			task.AddCommand(command)
-			config.AddDefinedTask(task)
-		} else if node, ok := rootNode.(Node); ok {
-			task = NewDefinedTask(taskName)
-			parseNode(task, node)
-			config.AddDefinedTask(task)
+		task := NewDefinedTask(f.taskName(taskName))
+		if hidden {
+			task.SetHidden(true)
+		}
+		for _, variable := range f.env {
+			pair := strings.SplitN(variable, "=", 2)
+			task.AddEnv(pair[0], pair[1])
+		}
+		for _, v := range f.vars {
+			task.AddVar(v)
 		}
+		rootNode := values[i]
+		if isString(rootNode) {
+			task.AddCommand(rootNode.Value)
+		} else if rootNode.Kind == yaml.MappingNode {
+			if err := f.parseDefinition(task, rootNode); err != nil {
+				return err
+			}
+		} else if rootNode.Kind == yaml.SequenceNode {
+			if err := parseNode(task, rootNode); err != nil {
+				return err
+			}
+		} else {
+			continue
+		}
+		task.SetRootDir(rootDir)
+		task.SetSource(f.path, key.Line)
+
+		f.addDefinedTask(task)
 	}
 
-	config.sortDefinedTasks()
+	for _, node := range includes {
+		if err := f.parseIncludes(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return config, nil
+// parseDotenv loads the dotenv files shared by all tasks of the file.
+func (f *configFile) parseDotenv(node *yaml.Node) error {
+	paths, err := parseStringList(node)
+	if err != nil {
+		return fmt.Errorf("dotenv %s. path: %s", err.Error(), f.path)
+	}
+	variables, err := loadDotenvFiles(filepath.Dir(f.path), paths, f.env)
+	if err != nil {
+		return err
+	}
+	for _, variable := range variables {
+		pair := strings.SplitN(variable, "=", 2)
+		f.env = setEnv(f.env, pair[0], pair[1])
+	}
+	return nil
+}
+
+// parseEnv parses the env shared by all tasks of the file.
+func (f *configFile) parseEnv(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("env must be a map. path: %s", f.path)
+	}
+	keys, values := mergedMapItems(node)
+	for i, key := range keys {
+		value := expandEnv(parseScalar(values[i]), f.env)
+		f.env = setEnv(f.env, key.Value, value)
+	}
+	return nil
+}
+
+// parseVars parses the vars shared by all tasks of the file.
+func (f *configFile) parseVars(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("vars must be a map. path: %s", f.path)
+	}
+	keys, values := mergedMapItems(node)
+	for i, key := range keys {
+		v, err := parseVar(key.Value, values[i])
+		if err != nil {
+			return fmt.Errorf("var %s. path: %s, var: %s", err.Error(), f.path, v.Name)
+		}
+		f.vars = setVar(f.vars, v)
+	}
+	return nil
+}
+
+// taskName returns the name of the task prefixed with the namespace of the file.
+func (f *configFile) taskName(name string) string {
+	if strings.HasPrefix(name, ":") {
+		return strings.TrimPrefix(name, ":")
+	}
+	if f.namespace == "" {
+		return name
+	}
+	return f.namespace + ":" + name
+}
+
+// DuplicateTaskError is a task whose name or alias is already used by another task,
+// e.g. in the config and an included file.
+type DuplicateTaskError struct {
+	Task string
+	// Name is the name or the alias used by Other, which is empty when the task is defined twice.
+	Name  string
+	Other string
+	Path  string
+	Line  int
+}
+
+func (e *DuplicateTaskError) Message() string {
+	if e.Name == "" {
+		return fmt.Sprintf("task is already defined. task: %s", e.Task)
+	}
+	return fmt.Sprintf("task name or alias is already used. task: %s, name: %s, other: %s", e.Task, e.Name, e.Other)
+}
+
+func (e *DuplicateTaskError) Error() string {
+	return (&ConfigError{Path: e.Path, Line: e.Line, Message: e.Message()}).Error()
+}
+
+// DuplicateTaskErrors is the list of the tasks whose names collide.
+type DuplicateTaskErrors []*DuplicateTaskError
+
+func (e DuplicateTaskErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// addDefinedTask adds the task, or records the collision and skips the task
+// so the problems of the other tasks are also found.
+func (f *configFile) addDefinedTask(task DefinedTask) {
+	if err := f.findDuplicate(task); err != nil {
+		f.config.duplicates = append(f.config.duplicates, err)
+		return
+	}
+	f.config.AddDefinedTask(task)
+}
+
+func (f *configFile) findDuplicate(task DefinedTask) *DuplicateTaskError {
+	for _, definedTask := range f.config.DefinedTasks() {
+		if definedTask.Name() == task.Name() {
+			return &DuplicateTaskError{Task: task.Name(), Path: f.path, Line: task.SourceLine()}
+		}
+	}
+	// Aliases in a file are checked by the validator, and the ones across files are checked here.
+	for _, name := range append([]string{task.Name()}, task.Aliases()...) {
+		if definedTask, found := findTaskByName(f.config.DefinedTasks(), name); found {
+			return &DuplicateTaskError{Task: task.Name(), Name: name, Other: definedTask.Name(), Path: f.path, Line: task.SourceLine()}
+		}
+	}
+	return nil
+}
+
+func (f *configFile) parseIncludes(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		for _, childNode := range node.Content {
+			if err := f.parseInclude(resolve(childNode)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return f.parseInclude(node)
+}
+
+func (f *configFile) parseInclude(node *yaml.Node) error {
+	var path, namespace string
+	var optional bool
+	if isString(node) {
+		path = node.Value
+	} else if node.Kind == yaml.MappingNode {
+		keys, values := mergedMapItems(node)
+		for i, key := range keys {
+			value := values[i]
+			switch key.Value {
+			case "file":
+				if !isString(value) {
+					return fmt.Errorf("file of include must be a string. path: %s", f.path)
+				}
+				path = value.Value
+			case "namespace":
+				if !isString(value) {
+					return fmt.Errorf("namespace of include must be a string. path: %s", f.path)
+				}
+				namespace = value.Value
+			case "optional":
+				b, err := parseBool(value)
+				if err != nil {
+					return fmt.Errorf("optional of include must be a boolean. path: %s", f.path)
+				}
+				optional = b
+			}
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("include must be a file path or a map with file. path: %s", f.path)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(f.path), path)
+	}
+
+	if err := f.checkIncludeCycle(path); err != nil {
+		return err
+	}
+
+	includedNamespace := f.namespace
+	if namespace != "" {
+		includedNamespace = f.taskName(namespace)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// A file included by several files, such as a shared file, is parsed once per namespace.
+	if f.included == nil {
+		f.included = map[string]bool{}
+	}
+	key := absPath + "\x00" + includedNamespace
+	if f.included[key] {
+		Debug("Skipped include because the file is already included. path: %s, namespace: %s", path, includedNamespace)
+		return nil
+	}
+	f.included[key] = true
+
+	if optional {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			Debug("Skipped optional include because the file does not exist. path: %s", path)
+			return nil
+		}
+	}
+
+	buf, err := ReadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	Debug("Include config file. path: %s, namespace: %s", path, namespace)
+	included := &configFile{
+		config:    f.config,
+		path:      path,
+		namespace: includedNamespace,
+		env:       append([]string{}, f.env...),
+		vars:      append([]Var{}, f.vars...),
+		parent:    f,
+		included:  f.included,
+	}
+	if err := included.parse(buf); err != nil {
+		return err
+	}
+	return nil
 }
 
-func parseNode(task DefinedTask, node Node) {
-	if command, ok := node.(string); ok {
-		task.AddCommand(command)
-	} else if list, ok := node.([]interface{}); ok {
-		for _, childNode := range list {
-			parseNode(task, childNode)
+func (f *configFile) checkIncludeCycle(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	cycle := []string{path}
+	for file := f; file != nil; file = file.parent {
+		cycle = append([]string{file.path}, cycle...)
+
+		filePath, err := filepath.Abs(file.path)
+		if err != nil {
+			return err
+		}
+		if filePath == absPath {
+			return fmt.Errorf("include cycle detected. cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+func parseNode(task DefinedTask, node *yaml.Node) error {
+	if isString(node) {
+		task.AddCommand(node.Value)
+	} else if node.Kind == yaml.SequenceNode {
+		for _, childNode := range node.Content {
+			if err := parseNode(task, resolve(childNode)); err != nil {
+				return err
+			}
+		}
+	} else if node.Kind == yaml.MappingNode {
+		return parseCommandDefinition(task, node)
+	}
+	return nil
+}
+
+func parseCommandDefinition(task DefinedTask, definition *yaml.Node) error {
+	var command string
+	var timeout time.Duration
+	keys, values := mergedMapItems(definition)
+	for i, key := range keys {
+		value := values[i]
+		switch key.Value {
+		case "cmd":
+			if !isString(value) {
+				return fmt.Errorf("cmd must be a string. task: %s", task.Name())
+			}
+			command = value.Value
+		case "timeout":
+			duration, err := parseDuration(value)
+			if err != nil {
+				return fmt.Errorf("timeout %s. task: %s", err.Error(), task.Name())
+			}
+			timeout = duration
+		}
+	}
+	task.AddCommandWithTimeout(command, timeout)
+	return nil
+}
+
+func (f *configFile) parseDefinition(task DefinedTask, definition *yaml.Node) error {
+	keys, values := mergedMapItems(definition)
+
+	// The variables of dotenv files are overridden by env regardless of the order of the keys.
+	for i, key := range keys {
+		if key.Value != "dotenv" {
+			continue
+		}
+		paths, err := parseStringList(values[i])
+		if err != nil {
+			return fmt.Errorf("dotenv %s. task: %s", err.Error(), task.Name())
+		}
+		variables, err := loadDotenvFiles(filepath.Dir(f.path), paths, task.Env())
+		if err != nil {
+			return err
+		}
+		for _, variable := range variables {
+			pair := strings.SplitN(variable, "=", 2)
+			task.AddEnv(pair[0], pair[1])
+		}
+	}
+
+	for i, key := range keys {
+		value := values[i]
+		switch key.Value {
+		case "desc":
+			if !isString(value) {
+				return fmt.Errorf("desc must be a string. task: %s", task.Name())
+			}
+			task.SetDescription(value.Value)
+		case "cmds":
+			if err := parseNode(task, value); err != nil {
+				return err
+			}
+		case "deps":
+			deps, err := parseStringList(value)
+			if err != nil {
+				return fmt.Errorf("deps %s. task: %s", err.Error(), task.Name())
+			}
+			for _, dep := range deps {
+				task.AddDependency(f.taskName(dep))
+			}
+		case "env":
+			if value.Kind != yaml.MappingNode {
+				return fmt.Errorf("env must be a map. task: %s", task.Name())
+			}
+			envKeys, envValues := mergedMapItems(value)
+			for j, envKey := range envKeys {
+				task.AddEnv(envKey.Value, expandEnv(parseScalar(envValues[j]), task.Env()))
+			}
+		case "vars":
+			if value.Kind != yaml.MappingNode {
+				return fmt.Errorf("vars must be a map. task: %s", task.Name())
+			}
+			varKeys, varValues := mergedMapItems(value)
+			for j, varKey := range varKeys {
+				v, err := parseVar(varKey.Value, varValues[j])
+				if err != nil {
+					return fmt.Errorf("var %s. task: %s, var: %s", err.Error(), task.Name(), v.Name)
+				}
+				task.AddVar(v)
+			}
+		case "params":
+			if value.Kind != yaml.SequenceNode {
+				return fmt.Errorf("params must be a list. task: %s", task.Name())
+			}
+			for _, node := range value.Content {
+				param, err := parseParam(resolve(node))
+				if err != nil {
+					return fmt.Errorf("params %s. task: %s", err.Error(), task.Name())
+				}
+				task.AddParam(param)
+			}
+		case "aliases":
+			aliases, err := parseStringList(value)
+			if err != nil {
+				return fmt.Errorf("aliases %s. task: %s", err.Error(), task.Name())
+			}
+			for _, alias := range aliases {
+				task.AddAlias(f.taskName(alias))
+			}
+		case "tags":
+			tags, err := parseStringList(value)
+			if err != nil {
+				return fmt.Errorf("tags %s. task: %s", err.Error(), task.Name())
+			}
+			for _, tag := range tags {
+				task.AddTag(tag)
+			}
+		case "dir":
+			if !isString(value) {
+				return fmt.Errorf("dir must be a string. task: %s", task.Name())
+			}
+			task.SetDir(value.Value)
+		case "timeout":
+			timeout, err := parseDuration(value)
+			if err != nil {
+				return fmt.Errorf("timeout %s. task: %s", err.Error(), task.Name())
+			}
+			task.SetTimeout(timeout)
+		case "internal":
+			internal, err := parseBool(value)
+			if err != nil {
+				return fmt.Errorf("internal must be a boolean. task: %s", task.Name())
+			}
+			task.SetHidden(internal)
+		case "dotenv":
+			// Loaded above.
+		}
+	}
+	return nil
+}
+
+// parseVar parses a var which is a scalar or a map with sh.
+func parseVar(name string, node *yaml.Node) (Var, error) {
+	if node.Kind != yaml.MappingNode {
+		return Var{Name: name, Value: parseScalar(node)}, nil
+	}
+
+	v := Var{Name: name}
+	keys, values := mergedMapItems(node)
+	for i, key := range keys {
+		switch key.Value {
+		case "sh":
+			if !isString(values[i]) || values[i].Value == "" {
+				return v, fmt.Errorf("must be a scalar or a map with sh")
+			}
+			v.Sh = values[i].Value
+		}
+	}
+	if v.Sh == "" {
+		return v, fmt.Errorf("must be a scalar or a map with sh")
+	}
+	return v, nil
+}
+
+// parseParam parses a param which is a name or a map with name, default, required, enum and desc.
+func parseParam(node *yaml.Node) (Param, error) {
+	if isString(node) {
+		return Param{Name: node.Value}, nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return Param{}, fmt.Errorf("must be a name or a map with name")
+	}
+
+	var param Param
+	keys, values := mergedMapItems(node)
+	for i, key := range keys {
+		value := values[i]
+		switch key.Value {
+		case "name":
+			if !isString(value) {
+				return param, fmt.Errorf("must be a name or a map with name")
+			}
+			param.Name = value.Value
+		case "default":
+			param.Default = parseScalar(value)
+			param.HasDefault = true
+		case "required":
+			required, err := parseBool(value)
+			if err != nil {
+				return param, fmt.Errorf("required must be a boolean")
+			}
+			param.Required = required
+		case "enum":
+			if value.Kind != yaml.SequenceNode {
+				return param, fmt.Errorf("enum must be a list")
+			}
+			for _, allowed := range value.Content {
+				param.Enum = append(param.Enum, parseScalar(resolve(allowed)))
+			}
+		case "desc":
+			if !isString(value) {
+				return param, fmt.Errorf("desc must be a string")
+			}
+			param.Description = value.Value
 		}
 	}
+	if !variablePattern.MatchString(param.Name + "=") {
+		return param, fmt.Errorf("must be a name or a map with name")
+	}
+	return param, nil
+}
+
+func parseStringList(node *yaml.Node) ([]string, error) {
+	if isString(node) {
+		return []string{node.Value}, nil
+	} else if node.Kind == yaml.SequenceNode {
+		var ret []string
+		for _, childNode := range node.Content {
+			childNode = resolve(childNode)
+			if !isString(childNode) {
+				return nil, fmt.Errorf("must be a list of strings")
+			}
+			ret = append(ret, childNode.Value)
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("must be a string or a list of strings")
+}
+
+func parseDuration(node *yaml.Node) (time.Duration, error) {
+	if !isString(node) {
+		return 0, fmt.Errorf("must be a duration such as 30s or 5m")
+	}
+	duration, err := time.ParseDuration(node.Value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("must be a duration such as 30s or 5m")
+	}
+	return duration, nil
+}
+
+func parseBool(node *yaml.Node) (bool, error) {
+	var b bool
+	if node.ShortTag() != "!!bool" {
+		return b, fmt.Errorf("must be a boolean")
+	}
+	err := node.Decode(&b)
+	return b, err
+}
+
+// parseScalar returns the scalar as written in the config, so `yes` and `1.0` are kept as they are.
+func parseScalar(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+		return ""
+	}
+	return node.Value
 }
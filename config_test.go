@@ -3,15 +3,32 @@ package main
 import (
 	assert2 "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 )
 
 type MockConfig struct {
 	mock.Mock
 }
 
-func (m *MockConfig) ShowAllDefinedTasks() {
-	m.Called()
+func (m *MockConfig) Path() string {
+	return m.Called().String(0)
+}
+
+func (m *MockConfig) ShowTaskHelp(name string) error {
+	ret := m.Called(name).Get(0)
+	if v, ok := ret.(error); ok {
+		return v
+	}
+	return nil
+}
+
+func (m *MockConfig) ShowAllDefinedTasks(filter *regexp.Regexp, showsHidden bool, usesJSON bool) {
+	m.Called(filter, showsHidden, usesJSON)
 }
 
 func (m *MockConfig) AddDefinedTask(task DefinedTask) {
@@ -130,7 +147,7 @@ func TestConfigImpl_ShowAllDefinedTasks(t *testing.T) {
 			},
 		}
 
-		config.ShowAllDefinedTasks()
+		config.ShowAllDefinedTasks(nil, false, false)
 
 		expected := "All defined tasks:\n\nfoo\n"
 		assert.Equal(expected, iobuffer.String())
@@ -152,11 +169,251 @@ func TestConfigImpl_ShowAllDefinedTasks(t *testing.T) {
 			},
 		}
 
-		config.ShowAllDefinedTasks()
+		config.ShowAllDefinedTasks(nil, false, false)
 
 		expected := "All defined tasks:\n\nfoo\nbar\n"
 		assert.Equal(expected, iobuffer.String())
 	})
+
+	t.Run("When the task has params.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		config := ConfigImpl{
+			definedTasks: []DefinedTask{
+				&DefinedTaskImpl{
+					name:   "deploy",
+					params: []Param{{Name: "ENV", Required: true}},
+				},
+			},
+		}
+
+		config.ShowAllDefinedTasks(nil, false, false)
+
+		expected := "All defined tasks:\n\ndeploy\n  ENV (required)\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	namespacedConfig := ConfigImpl{
+		definedTasks: []DefinedTask{
+			&DefinedTaskImpl{name: "build", description: "Build the binary.\nWith details.", aliases: []string{"b"}},
+			&DefinedTaskImpl{name: "go", description: "Run go."},
+			&DefinedTaskImpl{name: "go:lint"},
+			&DefinedTaskImpl{name: "go:test", description: "Run tests.", params: []Param{{Name: "PKG", Default: "./...", HasDefault: true}}},
+			&DefinedTaskImpl{name: "docker:image:push", aliases: []string{"push"}, description: "Push the image.", dependencies: []string{"build"}, tags: []string{"release"}, sourcePath: "taskal.yml", sourceLine: 12},
+		},
+	}
+
+	t.Run("When the tasks have namespaces and descriptions.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		namespacedConfig.ShowAllDefinedTasks(nil, false, false)
+
+		expected := "All defined tasks:\n\n" +
+			"build     Build the binary. (aliases: b)\n" +
+			"go        Run go.\n" +
+			"  lint\n" +
+			"  test    Run tests.\n" +
+			"    PKG (default: ./...)\n" +
+			"docker:\n" +
+			"  image:\n" +
+			"    push  Push the image. (aliases: push) (tags: release)\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the tasks are hidden.", func(t *testing.T) {
+		config := ConfigImpl{
+			definedTasks: []DefinedTask{
+				&DefinedTaskImpl{name: "_prepare", hidden: true},
+				&DefinedTaskImpl{name: "build"},
+				&DefinedTaskImpl{name: "setup", description: "Set up.", hidden: true},
+			},
+		}
+
+		t.Run("And hidden tasks are not shown.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			config.ShowAllDefinedTasks(nil, false, false)
+
+			expected := "All defined tasks:\n\nbuild\n"
+			assert.Equal(expected, iobuffer.String())
+		})
+
+		t.Run("And hidden tasks are shown.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			config.ShowAllDefinedTasks(nil, true, false)
+
+			expected := "All defined tasks:\n\n_prepare\nbuild\nsetup     Set up.\n"
+			assert.Equal(expected, iobuffer.String())
+		})
+	})
+
+	t.Run("When the filter is given.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		namespacedConfig.ShowAllDefinedTasks(regexp.MustCompile("^go:"), false, false)
+
+		expected := "All defined tasks:\n\n" +
+			"go:\n" +
+			"  lint\n" +
+			"  test  Run tests.\n" +
+			"    PKG (default: ./...)\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the output is JSON.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		namespacedConfig.ShowAllDefinedTasks(regexp.MustCompile("test|push"), false, true)
+
+		expected := `[
+  {
+    "name": "go:test",
+    "aliases": [],
+    "description": "Run tests.",
+    "params": [
+      {
+        "name": "PKG",
+        "description": "",
+        "default": "./...",
+        "required": false,
+        "enum": []
+      }
+    ],
+    "deps": [],
+    "tags": [],
+    "hidden": false,
+    "path": "",
+    "line": 0
+  },
+  {
+    "name": "docker:image:push",
+    "aliases": [
+      "push"
+    ],
+    "description": "Push the image.",
+    "params": [],
+    "deps": [
+      "build"
+    ],
+    "tags": [
+      "release"
+    ],
+    "hidden": false,
+    "path": "taskal.yml",
+    "line": 12
+  }
+]
+`
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When no tasks match in JSON.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		namespacedConfig.ShowAllDefinedTasks(regexp.MustCompile("notexists"), false, true)
+
+		expected := "[]\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
+
+func TestConfigImpl_ShowTaskHelp(t *testing.T) {
+	config := ConfigImpl{
+		definedTasks: []DefinedTask{
+			&DefinedTaskImpl{
+				name:         "deploy",
+				description:  "Deploy the application.",
+				aliases:      []string{"release"},
+				dependencies: []string{"build"},
+				params: []Param{
+					{Name: "ENV", Default: "staging", HasDefault: true, Description: "Target environment."},
+					{Name: "REGION", Required: true},
+				},
+			},
+			&DefinedTaskImpl{
+				name: "build",
+			},
+		},
+	}
+
+	t.Run("When the task has params.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		err := config.ShowTaskHelp("deploy")
+
+		assert.NoError(err)
+
+		expected := "Task: deploy\n" +
+			"  Deploy the application.\n" +
+			"\n" +
+			"Aliases: release\n" +
+			"\n" +
+			"Dependencies: build\n" +
+			"\n" +
+			"Parameters:\n" +
+			"  ENV  Target environment. (default: staging)\n" +
+			"  REGION (required)\n" +
+			"\n" +
+			"Usage:\n" +
+			"  taskal deploy REGION=<value>\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the task has nothing.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		err := config.ShowTaskHelp("build")
+
+		assert.NoError(err)
+
+		expected := "Task: build\n\nUsage:\n  taskal build\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the alias of the task is given.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		err := config.ShowTaskHelp("release")
+
+		assert.NoError(err)
+
+		expected := "Task: deploy\n"
+		assert.Contains(iobuffer.String(), expected)
+	})
+
+	t.Run("When the task is not defined.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		err := config.ShowTaskHelp("publish")
+
+		assert.Error(err)
+
+		expected := "[ERROR][15:04:05] Task is not defined. task: publish\n"
+		assert.Equal(expected, iobuffer.String())
+	})
 }
 
 func TestReadConfig(t *testing.T) {
@@ -196,6 +453,94 @@ func TestReadConfig(t *testing.T) {
 	})
 }
 
+func TestFindConfig(t *testing.T) {
+	t.Run("When the config file exists in the directory.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		dir, err := filepath.Abs("./fixtures/discovery")
+		assert.NoError(err)
+
+		actual, err := FindConfig(dir)
+
+		expected := filepath.Join(dir, "taskal.yaml")
+		assert.Equal(expected, actual)
+
+		assert.NoError(err)
+	})
+
+	t.Run("When the config file exists in a parent directory.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		dir, err := filepath.Abs("./fixtures/discovery/sub/dir")
+		assert.NoError(err)
+
+		actual, err := FindConfig(dir)
+
+		expected := filepath.Join(filepath.Dir(filepath.Dir(dir)), "taskal.yaml")
+		assert.Equal(expected, actual)
+
+		assert.NoError(err)
+	})
+
+	t.Run("When the config file does not exist.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		dir, err := ioutil.TempDir("", "taskal")
+		assert.NoError(err)
+		defer os.RemoveAll(dir)
+
+		_, err = FindConfig(dir)
+
+		assert.Error(err)
+
+		expected := "[ERROR][15:04:05] Config file not found. names: taskal.yml, taskal.yaml, .taskal.yml\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	originConfig := os.Getenv("TASKAL_CONFIG")
+	defer os.Setenv("TASKAL_CONFIG", originConfig)
+
+	t.Run("When the path is specified.", func(t *testing.T) {
+		assert := assert2.New(t)
+		os.Setenv("TASKAL_CONFIG", "./fixtures/env.yml")
+
+		actual, err := ResolveConfigPath("./fixtures/dummy.yml")
+
+		expected := "./fixtures/dummy.yml"
+		assert.Equal(expected, actual)
+
+		assert.NoError(err)
+	})
+
+	t.Run("When TASKAL_CONFIG is set.", func(t *testing.T) {
+		assert := assert2.New(t)
+		os.Setenv("TASKAL_CONFIG", "./fixtures/env.yml")
+
+		actual, err := ResolveConfigPath("")
+
+		expected := "./fixtures/env.yml"
+		assert.Equal(expected, actual)
+
+		assert.NoError(err)
+	})
+
+	t.Run("When nothing is specified.", func(t *testing.T) {
+		assert := assert2.New(t)
+		os.Setenv("TASKAL_CONFIG", "")
+
+		actual, err := ResolveConfigPath("")
+
+		expected, _ := filepath.Abs("taskal.yml")
+		assert.Equal(expected, actual)
+
+		assert.NoError(err)
+	})
+}
+
 func TestParseConfig(t *testing.T) {
 	originNewDefinedTask := NewDefinedTask
 
@@ -211,13 +556,13 @@ func TestParseConfig(t *testing.T) {
 		assert := assert2.New(t)
 
 		buf := "invalid yaml"
-		actual, err := ParseConfig(buf)
+		actual, err := ParseConfig("taskal.yml", buf)
 
 		assert.Nil(actual)
 
 		assert.Error(err)
 
-		expected := "[ERROR][15:04:05] yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `invalid...` into main.Document\n"
+		expected := "[ERROR][15:04:05] taskal.yml:1:1: config must be a map of tasks\n"
 		assert.Equal(expected, iobuffer.String())
 	})
 
@@ -226,22 +571,122 @@ func TestParseConfig(t *testing.T) {
 			assert := assert2.New(t)
 
 			buf := "_foo: echo foo"
-			actual, err := ParseConfig(buf)
+			actual, err := ParseConfig("taskal.yml", buf)
 
 			expected := (*Config)(nil)
 			assert.Implements(expected, actual)
 
 			assert.NoError(err)
 
-			expected2 := 0
+			expected2 := 1
 			assert.Len(actual.DefinedTasks(), expected2)
+
+			expected3 := "_foo"
+			assert.Equal(expected3, actual.DefinedTasks()[0].Name())
+
+			assert.True(actual.DefinedTasks()[0].Hidden())
+		})
+
+		t.Run("Has entry starting underscore which is not a task.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "_env: &env\n  FOO: foo\nfoo:\n  env: *env\n  cmds: echo $FOO"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := 1
+			assert.Len(actual.DefinedTasks(), expected)
+
+			expected2 := "foo"
+			assert.Equal(expected2, actual.DefinedTasks()[0].Name())
+		})
+
+		t.Run("Has tags.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "lint:\n  tags: ci\n  cmds: golint\ntest:\n  tags: [ci, slow]\n  cmds: go test"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := []string{"ci"}
+			assert.Equal(expected, actual.DefinedTasks()[0].Tags())
+
+			expected2 := []string{"ci", "slow"}
+			assert.Equal(expected2, actual.DefinedTasks()[1].Tags())
+		})
+
+		t.Run("Has aliases.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "build:\n  aliases: [b, compile]\n  cmds: go build\ntest:\n  aliases: t\n  cmds: go test"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := []string{"b", "compile"}
+			assert.Equal(expected, actual.DefinedTasks()[0].Aliases())
+
+			expected2 := []string{"t"}
+			assert.Equal(expected2, actual.DefinedTasks()[1].Aliases())
+		})
+
+		t.Run("Has internal task.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "setup:\n  internal: true\n  cmds: echo setup\n_check:\n  internal: false\n  cmds: echo check"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := "_check"
+			assert.Equal(expected, actual.DefinedTasks()[0].Name())
+			assert.False(actual.DefinedTasks()[0].Hidden())
+
+			expected2 := "setup"
+			assert.Equal(expected2, actual.DefinedTasks()[1].Name())
+			assert.True(actual.DefinedTasks()[1].Hidden())
+		})
+
+		t.Run("Has values which are booleans in YAML 1.1.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "a: yes\nb:\n  env:\n    X: yes\n    Y: 1.0\n  cmds: [on, off]"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := []string{"yes"}
+			assert.Equal(expected, actual.DefinedTasks()[0].Commands())
+
+			expected2 := []string{"on", "off"}
+			assert.Equal(expected2, actual.DefinedTasks()[1].Commands())
+			assert.Contains(actual.DefinedTasks()[1].Env(), "X=yes")
+			assert.Contains(actual.DefinedTasks()[1].Env(), "Y=1.0")
+		})
+
+		t.Run("Has merged definition.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "_base: &base\n  env:\n    FOO: foo\n  cmds: echo base\nfoo:\n  <<: *base\n  cmds: echo foo"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := "foo"
+			assert.Equal(expected, actual.DefinedTasks()[1].Name())
+
+			expected2 := []string{"echo foo"}
+			assert.Equal(expected2, actual.DefinedTasks()[1].Commands())
+			assert.Contains(actual.DefinedTasks()[1].Env(), "FOO=foo")
 		})
 
 		t.Run("Has once task.", func(t *testing.T) {
 			assert := assert2.New(t)
 
-			buf := "_foo: echo _foo\nfoo: echo foo"
-			actual, err := ParseConfig(buf)
+			buf := "_foo:\n  FOO: foo\nfoo: echo foo"
+			actual, err := ParseConfig("taskal.yml", buf)
 
 			expected := (*Config)(nil)
 			assert.Implements(expected, actual)
@@ -264,7 +709,7 @@ func TestParseConfig(t *testing.T) {
 			assert := assert2.New(t)
 
 			buf := "foo: echo foo\nbar: echo bar"
-			actual, err := ParseConfig(buf)
+			actual, err := ParseConfig("taskal.yml", buf)
 
 			expected := (*Config)(nil)
 			assert.Implements(expected, actual)
@@ -303,7 +748,7 @@ func TestParseConfig(t *testing.T) {
 				"    - echo baz\n" +
 				"    - echo buzz\n" +
 				""
-			actual, err := ParseConfig(buf)
+			actual, err := ParseConfig("taskal.yml", buf)
 
 			expected := (*Config)(nil)
 			assert.Implements(expected, actual)
@@ -341,4 +786,537 @@ func TestParseConfig(t *testing.T) {
 			assert.Equal(expected8, actual.DefinedTasks()[2].Commands())
 		})
 	})
+
+	t.Run("When passing structured task.", func(t *testing.T) {
+		t.Run("Has all keys.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			buf := "build:\n" +
+				"  desc: Build the binary.\n" +
+				"  deps: [lint, test]\n" +
+				"  env:\n" +
+				"    GOOS: linux\n" +
+				"    CGO_ENABLED: 0\n" +
+				"  dir: ./cmd\n" +
+				"  cmds:\n" +
+				"    - go build\n" +
+				"    -\n" +
+				"      - echo done\n" +
+				"lint: golint\n" +
+				"test: go test\n" +
+				""
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := 3
+			assert.Len(actual.DefinedTasks(), expected)
+
+			task := actual.DefinedTasks()[0]
+
+			expected2 := "build"
+			assert.Equal(expected2, task.Name())
+
+			expected3 := "Build the binary."
+			assert.Equal(expected3, task.Description())
+
+			expected4 := []string{"lint", "test"}
+			assert.Equal(expected4, task.Dependencies())
+
+			expected5 := []string{"GOOS=linux", "CGO_ENABLED=0"}
+			assert.Equal(expected5, task.Env())
+
+			expected6 := "./cmd"
+			assert.Equal(expected6, task.Dir())
+
+			expected7 := []string{"go build", "echo done"}
+			assert.Equal(expected7, task.Commands())
+		})
+
+		t.Run("Has single dependency.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "build:\n  deps: lint\n  cmds: go build\nlint: golint\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := []string{"lint"}
+			assert.Equal(expected, actual.DefinedTasks()[0].Dependencies())
+		})
+
+		t.Run("Has unknown key.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			buf := "build:\n  cmd: go build\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			assert.IsType(ConfigErrors{}, err)
+
+			expected := "[ERROR][15:04:05] taskal.yml:2:3: unknown key in task definition: cmd. task: build\n"
+			assert.Equal(expected, iobuffer.String())
+		})
+
+		t.Run("Has invalid deps.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			buf := "build:\n  deps:\n    lint: golint\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			assert.Error(err)
+
+			expected := "[ERROR][15:04:05] taskal.yml:3:5: deps must be a string or a list of strings. task: build\n"
+			assert.Contains(iobuffer.String(), expected)
+		})
+
+		t.Run("Has timeouts.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "test:\n" +
+				"  timeout: 10m\n" +
+				"  cmds:\n" +
+				"    - go vet\n" +
+				"    - cmd: go test\n" +
+				"      timeout: 5m\n" +
+				""
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.NoError(err)
+
+			task := actual.DefinedTasks()[0]
+
+			expected := 10 * time.Minute
+			assert.Equal(expected, task.Timeout())
+
+			expected2 := []string{"go vet", "go test"}
+			assert.Equal(expected2, task.Commands())
+
+			expected3 := map[int]time.Duration{1: 5 * time.Minute}
+			assert.Equal(expected3, task.(*DefinedTaskImpl).commandTimeouts)
+		})
+
+		t.Run("Has tasks in the config file directory.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "build:\n  dir: ./src\n  cmds: go build\n"
+			actual, err := ParseConfig("/path/to/project/taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := "/path/to/project/src"
+			assert.Equal(expected, actual.DefinedTasks()[0].WorkingDir())
+
+			expected2 := "/path/to/project/taskal.yml"
+			assert.Equal(expected2, actual.Path())
+		})
+
+		t.Run("Has invalid timeout.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "test:\n  timeout: ten minutes\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			expected := "taskal.yml:2:12: timeout must be a duration such as 30s or 5m. task: test"
+			assert.EqualError(err, expected)
+		})
+
+		t.Run("Has invalid command timeout.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "test:\n  - cmd: go test\n    timeout: 5\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			expected := "taskal.yml:3:14: timeout must be a duration such as 30s or 5m. task: test"
+			assert.EqualError(err, expected)
+		})
+
+		t.Run("Has invalid env.", func(t *testing.T) {
+			iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			buf := "build:\n  env: FOO=foo\n"
+			actual, err := ParseConfig("taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			expected := "taskal.yml:2:8: env must be a map. task: build"
+			assert.EqualError(err, expected)
+		})
+	})
+}
+
+func TestParseConfig_include(t *testing.T) {
+	t.Run("When including a file without namespace.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include: shared/lint.yml\nfmt: gofmt -l .\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := 2
+		assert.Len(actual.DefinedTasks(), expected)
+
+		expected2 := "lint"
+		assert.Equal(expected2, actual.DefinedTasks()[1].Name())
+
+		expected3 := []string{"fmt"}
+		assert.Equal(expected3, actual.DefinedTasks()[1].Dependencies())
+
+		expected4, _ := filepath.Abs("./fixtures/include/shared")
+		assert.Equal(expected4, actual.DefinedTasks()[1].WorkingDir())
+
+		expected5 := "./fixtures/include/taskal.yml"
+		assert.Equal(expected5, actual.DefinedTasks()[0].SourcePath())
+		expected6 := 2
+		assert.Equal(expected6, actual.DefinedTasks()[0].SourceLine())
+
+		expected7 := "fixtures/include/shared/lint.yml"
+		assert.Equal(expected7, actual.DefinedTasks()[1].SourcePath())
+		expected8 := 1
+		assert.Equal(expected8, actual.DefinedTasks()[1].SourceLine())
+	})
+
+	t.Run("When including a file with aliases.", func(t *testing.T) {
+		t.Run("And the file has a namespace.", func(t *testing.T) {
+			assert := assert2.New(t)
+
+			buf := "include:\n  - file: shared/alias.yml\n    namespace: go\n"
+			actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+			assert.NoError(err)
+
+			expected := []string{"go:b"}
+			assert.Equal(expected, actual.DefinedTasks()[0].Aliases())
+		})
+
+		t.Run("And the alias collides with a task in the file.", func(t *testing.T) {
+			iobuffer.Reset()
+			defer iobuffer.Reset()
+
+			assert := assert2.New(t)
+
+			buf := "b: echo b\ninclude: shared/alias.yml\n"
+			actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+			assert.Nil(actual)
+
+			expected := "fixtures/include/shared/alias.yml:1: task name or alias is already used. task: build, name: b, other: b"
+			assert.EqualError(err, expected)
+		})
+	})
+
+	t.Run("When include and dotenv are task definitions.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include:\n  cmds: ./include.sh\ndotenv:\n  desc: Show the dotenv file.\n  cmds: cat .env\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []string{"dotenv", "include"}
+		assert.Equal(expected, taskNames(actual.DefinedTasks()))
+
+		expected2 := []string{"cat .env"}
+		assert.Equal(expected2, actual.DefinedTasks()[0].Commands())
+	})
+
+	t.Run("When including a file with namespace.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include:\n  - file: shared/go.yml\n    namespace: go\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := 2
+		assert.Len(actual.DefinedTasks(), expected)
+
+		expected2 := "go:build"
+		assert.Equal(expected2, actual.DefinedTasks()[0].Name())
+
+		expected3 := []string{"go:test"}
+		assert.Equal(expected3, actual.DefinedTasks()[0].Dependencies())
+
+		expected4 := "go:test"
+		assert.Equal(expected4, actual.DefinedTasks()[1].Name())
+	})
+
+	t.Run("When the same file is included by several files.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include:\n  - diamond_b.yml\n  - diamond_c.yml\n  - file: shared/fmt.yml\n    namespace: go\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []string{"b", "c", "fmt", "go:fmt"}
+		assert.Equal(expected, taskNames(actual.DefinedTasks()))
+	})
+
+	t.Run("When including an optional file which does not exist.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "_include:\n  - file: notexists.yml\n    optional: true\nfoo: echo foo\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := 1
+		assert.Len(actual.DefinedTasks(), expected)
+	})
+
+	t.Run("When including a file which does not exist.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include: notexists.yml\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		assert.Error(err)
+	})
+
+	t.Run("When the task name collides.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include: shared/go.yml\ntest: go test -race ./...\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "fixtures/include/shared/go.yml:1: task is already defined. task: test"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the includes are cyclic.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include: cycle_a.yml\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "include cycle detected. cycle: fixtures/include/cycle_a.yml -> fixtures/include/cycle_b.yml -> fixtures/include/cycle_a.yml"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the include has no file.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include:\n  - namespace: go\n"
+		actual, err := ParseConfig("./fixtures/include/taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "./fixtures/include/taskal.yml:2:5: include must be a file path or a map with file"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestParseConfig_env(t *testing.T) {
+	os.Setenv("TASKAL_TEST_HOME", "/home/taskal")
+	defer os.Unsetenv("TASKAL_TEST_HOME")
+
+	t.Run("When env is defined globally and in the task.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  env:\n    GOOS: ${GOOS}-${GOARCH}\n  cmds: go build\n" +
+			"env:\n  GOPATH: ${TASKAL_TEST_HOME}/go\n  GOBIN: ${GOPATH}/bin\n  GOOS: linux\n  GOARCH: amd64\n" +
+			"test: go test\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := 2
+		assert.Len(actual.DefinedTasks(), expected)
+
+		expected2 := []string{"GOPATH=/home/taskal/go", "GOBIN=/home/taskal/go/bin", "GOOS=linux-amd64", "GOARCH=amd64"}
+		assert.Equal(expected2, actual.DefinedTasks()[0].Env())
+
+		expected3 := []string{"GOPATH=/home/taskal/go", "GOBIN=/home/taskal/go/bin", "GOOS=linux", "GOARCH=amd64"}
+		assert.Equal(expected3, actual.DefinedTasks()[1].Env())
+	})
+
+	t.Run("When global env is not a map.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "env: printenv | sort\nvars: [echo foo, echo bar]\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []string{"env", "vars"}
+		assert.Equal(expected, taskNames(actual.DefinedTasks()))
+
+		expected2 := []string{"printenv | sort"}
+		assert.Equal(expected2, actual.DefinedTasks()[0].Commands())
+
+		expected3 := []string{"echo foo", "echo bar"}
+		assert.Equal(expected3, actual.DefinedTasks()[1].Commands())
+	})
+
+	t.Run("When global env is neither a map nor a task.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "env: 1\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "taskal.yml:1:6: task must be a command, a list of commands or a map. task: env"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestParseConfig_dotenv(t *testing.T) {
+	t.Run("When dotenv is defined globally and in the task.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "env:\n  GLOBAL_ENV: global-env\n  TASK_DOTENV: global-env\n  TASK_ENV: global-env\n" +
+			"dotenv: [.env, .env.local, .env.notexists]\n" +
+			"build:\n  env:\n    TASK_ENV: task-env\n  dotenv: .env.task\n  cmds: go build\n"
+		actual, err := ParseConfig("./fixtures/dotenv/taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []string{
+			"APP_ENV=local",
+			"APP_NAME=taskal app",
+			"DATABASE_URL=postgres://localhost/development",
+			"GLOBAL_DOTENV=global-dotenv",
+			"GLOBAL_ENV=global-env",
+			"TASK_DOTENV=task-dotenv",
+			"TASK_ENV=task-env",
+			"FROM_GLOBAL=global-env",
+		}
+		assert.Equal(expected, actual.DefinedTasks()[0].Env())
+	})
+
+	t.Run("When dotenv is not a list of strings.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  dotenv:\n    file: .env\n"
+		actual, err := ParseConfig("./fixtures/dotenv/taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "./fixtures/dotenv/taskal.yml:3:5: dotenv must be a string or a list of strings. task: build"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestParseConfig_vars(t *testing.T) {
+	t.Run("When vars are defined globally and in the task.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "vars:\n  BIN_DIR: bin\n  VERSION: v1\n" +
+			"build:\n  vars:\n    VERSION: v2\n    NAME: app\n  cmds: go build -o {{.BIN_DIR}}/{{.NAME}}\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []Var{
+			{Name: "BIN_DIR", Value: "bin"},
+			{Name: "VERSION", Value: "v2"},
+			{Name: "NAME", Value: "app"},
+		}
+		assert.Equal(expected, actual.DefinedTasks()[0].Vars())
+	})
+
+	t.Run("When vars have sh.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "vars:\n  GIT_SHA:\n    sh: git rev-parse --short HEAD\nbuild: echo {{.GIT_SHA}}\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []Var{{Name: "GIT_SHA", Sh: "git rev-parse --short HEAD"}}
+		assert.Equal(expected, actual.DefinedTasks()[0].Vars())
+	})
+
+	t.Run("When vars have a map without sh.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  vars:\n    GIT_SHA:\n      cmd: git rev-parse HEAD\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "taskal.yml:4:7: unknown key in var definition: cmd. task: build, var: GIT_SHA\ntaskal.yml:4:7: var must be a scalar or a map with sh. task: build, var: GIT_SHA"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When vars is not a map.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  vars: [BIN_DIR]\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "taskal.yml:2:9: vars must be a map. task: build"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestParseConfig_params(t *testing.T) {
+	t.Run("When params are defined.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "deploy:\n  params:\n    - VERSION\n" +
+			"    - name: ENV\n      default: staging\n      enum: [staging, production]\n      desc: Target environment.\n" +
+			"    - name: REGION\n      required: true\n" +
+			"  cmds: ./deploy.sh\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.NoError(err)
+
+		expected := []Param{
+			{Name: "VERSION"},
+			{Name: "ENV", Default: "staging", HasDefault: true, Enum: []string{"staging", "production"}, Description: "Target environment."},
+			{Name: "REGION", Required: true},
+		}
+		assert.Equal(expected, actual.DefinedTasks()[0].Params())
+	})
+
+	t.Run("When the param has no name.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "deploy:\n  params:\n    - default: staging\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "taskal.yml:3:7: param must be a name or a map with name. task: deploy"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When params is not a list.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "deploy:\n  params: ENV\n"
+		actual, err := ParseConfig("taskal.yml", buf)
+
+		assert.Nil(actual)
+
+		expected := "taskal.yml:2:11: params must be a list. task: deploy"
+		assert.EqualError(err, expected)
+	})
 }
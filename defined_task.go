@@ -1,20 +1,67 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/fatih/color"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type DefinedTask interface {
 	Name() string
+	Description() string
+	SetDescription(string)
 	AddCommand(string)
+	AddCommandWithTimeout(string, time.Duration)
 	Commands() []string
-	Run(bool, []string) error
+	AddDependency(string)
+	Dependencies() []string
+	AddEnv(string, string)
+	Env() []string
+	AddVar(Var)
+	Vars() []Var
+	OverrideVar(string, string)
+	AddParam(Param)
+	Params() []Param
+	AddTag(string)
+	Tags() []string
+	AddAlias(string)
+	Aliases() []string
+	Dir() string
+	SetDir(string)
+	SetRootDir(string)
+	WorkingDir() string
+	SetSource(string, int)
+	SourcePath() string
+	SourceLine() int
+	Hidden() bool
+	SetHidden(bool)
+	Timeout() time.Duration
+	SetTimeout(time.Duration)
+	Run(context.Context, bool, []string, Output) error
 }
 
 type DefinedTaskImpl struct {
-	name     string
-	commands []string
+	name         string
+	description  string
+	commands     []string
+	dependencies []string
+	env          []string
+	vars         []Var
+	overrides    []Var
+	params       []Param
+	tags         []string
+	aliases      []string
+	dir          string
+	rootDir      string
+	sourcePath   string
+	sourceLine   int
+	hidden       bool
+	timeout      time.Duration
+	// commandTimeouts holds timeouts of commands by their index.
+	commandTimeouts map[int]time.Duration
 }
 
 var NewDefinedTask = func(name string) DefinedTask {
@@ -28,33 +75,306 @@ func (d *DefinedTaskImpl) Name() string {
 	return d.name
 }
 
+func (d *DefinedTaskImpl) Description() string {
+	return d.description
+}
+
+func (d *DefinedTaskImpl) SetDescription(description string) {
+	Debug("  Set Description: %s", description)
+	d.description = strings.TrimSpace(description)
+}
+
 func (d *DefinedTaskImpl) AddCommand(command string) {
 	Debug("  Add Command: %s", command)
 	d.commands = append(d.commands, strings.TrimSpace(command))
 }
 
+func (d *DefinedTaskImpl) AddCommandWithTimeout(command string, timeout time.Duration) {
+	d.AddCommand(command)
+	if timeout > 0 {
+		Debug("  Set Command Timeout: %s", timeout)
+		if d.commandTimeouts == nil {
+			d.commandTimeouts = make(map[int]time.Duration)
+		}
+		d.commandTimeouts[len(d.commands)-1] = timeout
+	}
+}
+
 func (d *DefinedTaskImpl) Commands() []string {
 	return d.commands
 }
 
-func (d *DefinedTaskImpl) Run(dryRun bool, args []string) error {
-	Info(color.HiYellowString("Execute task: %s", d.name))
+func (d *DefinedTaskImpl) AddDependency(name string) {
+	Debug("  Add Dependency: %s", name)
+	d.dependencies = append(d.dependencies, strings.TrimSpace(name))
+}
+
+func (d *DefinedTaskImpl) Dependencies() []string {
+	return d.dependencies
+}
+
+func (d *DefinedTaskImpl) AddEnv(key string, value string) {
+	Debug("  Add Env: %s=%s", key, value)
+	d.env = setEnv(d.env, key, value)
+}
+
+func (d *DefinedTaskImpl) Env() []string {
+	return d.env
+}
+
+func (d *DefinedTaskImpl) AddVar(v Var) {
+	if v.Sh != "" {
+		Debug("  Add Var: %s=$(%s)", v.Name, v.Sh)
+	} else {
+		Debug("  Add Var: %s=%s", v.Name, v.Value)
+	}
+	d.vars = setVar(d.vars, v)
+}
+
+func (d *DefinedTaskImpl) Vars() []Var {
+	return d.vars
+}
+
+// OverrideVar sets the variable which takes precedence over the vars of the task,
+// and which the vars can refer to.
+func (d *DefinedTaskImpl) OverrideVar(name string, value string) {
+	Debug("  Override Var: %s=%s", name, value)
+	d.overrides = setVar(d.overrides, Var{Name: name, Value: value})
+}
+
+func (d *DefinedTaskImpl) AddParam(param Param) {
+	Debug("  Add Param: %s", param.Name)
+	d.params = append(d.params, param)
+}
+
+func (d *DefinedTaskImpl) Params() []Param {
+	return d.params
+}
+
+func (d *DefinedTaskImpl) AddTag(tag string) {
+	Debug("  Add Tag: %s", tag)
+	d.tags = append(d.tags, tag)
+}
+
+func (d *DefinedTaskImpl) Tags() []string {
+	return d.tags
+}
+
+func (d *DefinedTaskImpl) AddAlias(alias string) {
+	Debug("  Add Alias: %s", alias)
+	d.aliases = append(d.aliases, alias)
+}
+
+// Aliases returns the other names of the task, such as the old names of a renamed task.
+func (d *DefinedTaskImpl) Aliases() []string {
+	return d.aliases
+}
+
+// findTaskByName finds the task by the name, or by the alias if no task has the name.
+func findTaskByName(tasks []DefinedTask, name string) (DefinedTask, bool) {
+	for _, task := range tasks {
+		if task.Name() == name {
+			return task, true
+		}
+	}
+	for _, task := range tasks {
+		for _, alias := range task.Aliases() {
+			if alias == name {
+				return task, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// hasAnyTag reports whether the task has any of the tags.
+func hasAnyTag(task DefinedTask, tags []string) bool {
+	for _, tag := range task.Tags() {
+		for _, t := range tags {
+			if tag == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (d *DefinedTaskImpl) Dir() string {
+	return d.dir
+}
+
+func (d *DefinedTaskImpl) SetDir(dir string) {
+	Debug("  Set Dir: %s", dir)
+	d.dir = dir
+}
+
+// SetRootDir sets the directory of the config file where the task is defined.
+func (d *DefinedTaskImpl) SetRootDir(dir string) {
+	d.rootDir = dir
+}
+
+// WorkingDir returns the directory where the commands are executed.
+// A relative dir is resolved from the directory of the config file.
+func (d *DefinedTaskImpl) WorkingDir() string {
+	if filepath.IsAbs(d.dir) {
+		return d.dir
+	}
+	return filepath.Join(d.rootDir, d.dir)
+}
+
+// SetSource sets the config file and the line where the task is defined.
+func (d *DefinedTaskImpl) SetSource(path string, line int) {
+	d.sourcePath = path
+	d.sourceLine = line
+}
+
+func (d *DefinedTaskImpl) SourcePath() string {
+	return d.sourcePath
+}
+
+func (d *DefinedTaskImpl) SourceLine() int {
+	return d.sourceLine
+}
+
+// Hidden reports whether the task is excluded from the task list.
+func (d *DefinedTaskImpl) Hidden() bool {
+	return d.hidden
+}
+
+func (d *DefinedTaskImpl) SetHidden(hidden bool) {
+	Debug("  Set Hidden: %t", hidden)
+	d.hidden = hidden
+}
+
+func (d *DefinedTaskImpl) Timeout() time.Duration {
+	return d.timeout
+}
+
+func (d *DefinedTaskImpl) SetTimeout(timeout time.Duration) {
+	Debug("  Set Timeout: %s", timeout)
+	d.timeout = timeout
+}
+
+func (d *DefinedTaskImpl) Run(ctx context.Context, dryRun bool, args []string, output Output) error {
+	InfoTo(output, color.HiYellowString("Execute task: %s", d.name))
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	rendered, err := d.render(ctx, dryRun, args, output)
+	if err != nil {
+		ErrorTo(output, "Failed to render task. task: %s, error: %s", d.name, err.Error())
+		return err
+	}
+
+	for i, command := range rendered.commands {
+		if ctx.Err() != nil {
+			err := contextError(ctx, command)
+			if err != context.Canceled {
+				ErrorTo(output, err.Error())
+			}
+			return err
+		}
 
-	commands := d.Commands()
-	for _, command := range commands {
-		if err := d.runOnce(dryRun, command, args); err != nil {
+		if err := rendered.runOnce(ctx, dryRun, command, args, output, d.commandTimeouts[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (d *DefinedTaskImpl) runOnce(dryRun bool, command string, args []string) error {
-	executor := NewExecutor(dryRun, command, args)
-	if err := executor.Execute(); err != nil {
-		Error(err.Error())
+// render returns a copy of the task whose commands, env and dir are rendered
+// with the built-in variables, the overridden variables and the vars of the task.
+// Vars are rendered in order, so they can refer to the built-in variables, the overridden variables
+// and the vars defined before.
+// Sh vars are evaluated only when they may be referred to.
+func (d *DefinedTaskImpl) render(ctx context.Context, dryRun bool, args []string, output Output) (*DefinedTaskImpl, error) {
+	texts := append(append([]string{d.dir}, d.commands...), d.env...)
+	for _, v := range d.vars {
+		texts = append(texts, v.Value, v.Sh)
+	}
+
+	data := builtinVars(d.name, d.rootDir, args)
+	overridden := map[string]bool{}
+	for _, v := range d.overrides {
+		data[v.Name] = v.Value
+		overridden[v.Name] = true
+	}
+	for _, v := range d.vars {
+		if overridden[v.Name] {
+			continue
+		}
+		if v.Sh == "" {
+			value, err := renderTemplate(v.Name, v.Value, data)
+			if err != nil {
+				return nil, err
+			}
+			data[v.Name] = value
+			continue
+		}
+
+		if !refersToVar(texts, v.Name) {
+			continue
+		}
+		command, err := renderTemplate(v.Name, v.Sh, data)
+		if err != nil {
+			return nil, err
+		}
+		value, err := shVars.Evaluate(ctx, dryRun, command, d.rootDir, d.env, output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate var. var: %s, error: %s", v.Name, err.Error())
+		}
+		data[v.Name] = value
+	}
+
+	rendered := *d
+	rendered.commands = nil
+	rendered.env = nil
+	for _, command := range d.commands {
+		renderedCommand, err := renderTemplate(d.name, command, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered.commands = append(rendered.commands, renderedCommand)
+	}
+	for _, variable := range d.env {
+		renderedVariable, err := renderTemplate(d.name, variable, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered.env = append(rendered.env, renderedVariable)
+	}
+	dir, err := renderTemplate(d.name, d.dir, data)
+	if err != nil {
+		return nil, err
+	}
+	rendered.dir = dir
+	return &rendered, nil
+}
+
+func (d *DefinedTaskImpl) runOnce(ctx context.Context, dryRun bool, command string, args []string, output Output, timeout time.Duration) error {
+	executor := NewExecutor(dryRun, command, args, d.WorkingDir(), d.env, output, timeout)
+	if err := executor.Execute(ctx); err != nil {
+		// The task is cancelled because another task failed, which is reported by the runner.
+		if err != context.Canceled {
+			ErrorTo(output, err.Error())
+		}
 		return err
 	} else {
 		return nil
 	}
 }
+
+// contextError returns the reason why the context is done before the command is executed.
+func contextError(ctx context.Context, command string) error {
+	if sig := runningCommands.Signal(); sig != nil {
+		return &InterruptedError{Signal: sig}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return &TimeoutError{Command: command}
+	}
+	return ctx.Err()
+}
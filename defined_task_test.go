@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	assert2 "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"runtime"
 	"testing"
+	"time"
 )
 
 type MockDefinedTask struct {
@@ -15,10 +18,22 @@ func (m *MockDefinedTask) Name() string {
 	return m.Called().String(0)
 }
 
+func (m *MockDefinedTask) Description() string {
+	return m.Called().String(0)
+}
+
+func (m *MockDefinedTask) SetDescription(description string) {
+	m.Called(description)
+}
+
 func (m *MockDefinedTask) AddCommand(command string) {
 	m.Called()
 }
 
+func (m *MockDefinedTask) AddCommandWithTimeout(command string, timeout time.Duration) {
+	m.Called(command, timeout)
+}
+
 func (m *MockDefinedTask) Commands() []string {
 	var ret []string
 	args := m.Called()
@@ -30,8 +45,142 @@ func (m *MockDefinedTask) Commands() []string {
 	return ret
 }
 
-func (m *MockDefinedTask) Run(dryRun bool, args []string) error {
-	ret := m.Called(dryRun, args).Get(0)
+func (m *MockDefinedTask) AddDependency(name string) {
+	m.Called(name)
+}
+
+func (m *MockDefinedTask) Dependencies() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		if v, ok := arg.(string); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) AddEnv(key string, value string) {
+	m.Called(key, value)
+}
+
+func (m *MockDefinedTask) Env() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		if v, ok := arg.(string); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) AddVar(v Var) {
+	m.Called(v)
+}
+
+func (m *MockDefinedTask) Vars() []Var {
+	var ret []Var
+	args := m.Called()
+	for _, arg := range args {
+		if v, ok := arg.(Var); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) OverrideVar(name string, value string) {
+	m.Called(name, value)
+}
+
+func (m *MockDefinedTask) AddParam(param Param) {
+	m.Called(param)
+}
+
+func (m *MockDefinedTask) Params() []Param {
+	var ret []Param
+	args := m.Called()
+	for _, arg := range args {
+		if v, ok := arg.(Param); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) Dir() string {
+	return m.Called().String(0)
+}
+
+func (m *MockDefinedTask) SetDir(dir string) {
+	m.Called(dir)
+}
+
+func (m *MockDefinedTask) SetRootDir(dir string) {
+	m.Called(dir)
+}
+
+func (m *MockDefinedTask) WorkingDir() string {
+	return m.Called().String(0)
+}
+
+func (m *MockDefinedTask) SetSource(path string, line int) {
+	m.Called(path, line)
+}
+
+func (m *MockDefinedTask) SourcePath() string {
+	return m.Called().String(0)
+}
+
+func (m *MockDefinedTask) SourceLine() int {
+	return m.Called().Int(0)
+}
+
+func (m *MockDefinedTask) Hidden() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockDefinedTask) SetHidden(hidden bool) {
+	m.Called(hidden)
+}
+
+func (m *MockDefinedTask) AddTag(tag string) {
+	m.Called(tag)
+}
+
+func (m *MockDefinedTask) Tags() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		ret = append(ret, arg.(string))
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) AddAlias(alias string) {
+	m.Called(alias)
+}
+
+func (m *MockDefinedTask) Aliases() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		ret = append(ret, arg.(string))
+	}
+	return ret
+}
+
+func (m *MockDefinedTask) Timeout() time.Duration {
+	return m.Called().Get(0).(time.Duration)
+}
+
+func (m *MockDefinedTask) SetTimeout(timeout time.Duration) {
+	m.Called(timeout)
+}
+
+func (m *MockDefinedTask) Run(ctx context.Context, dryRun bool, args []string, output Output) error {
+	ret := m.Called(ctx, dryRun, args).Get(0)
 	if v, ok := ret.(error); ok {
 		return v
 	} else {
@@ -39,6 +188,12 @@ func (m *MockDefinedTask) Run(dryRun bool, args []string) error {
 	}
 }
 
+type executorFunc func(context.Context) error
+
+func (f executorFunc) Execute(ctx context.Context) error {
+	return f(ctx)
+}
+
 func TestNewDefinedTask(t *testing.T) {
 	t.Run("When called this func.", func(t *testing.T) {
 		iobuffer.Reset()
@@ -86,6 +241,24 @@ func TestDefinedTaskImpl_Name(t *testing.T) {
 	})
 }
 
+func TestDefinedTaskImpl_SetDescription(t *testing.T) {
+	t.Run("When called this func.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.SetDescription("Build the binary.\n")
+
+		expected := "Build the binary."
+		assert.Equal(expected, task.Description())
+
+		expected2 := "[DEBUG][15:04:05]   Set Description: Build the binary.\n\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
 func TestDefinedTaskImpl_AddCommand(t *testing.T) {
 	t.Run("When called this func at once.", func(t *testing.T) {
 		iobuffer.Reset()
@@ -168,9 +341,183 @@ func TestDefinedTaskImpl_Commands(t *testing.T) {
 	})
 }
 
+func TestDefinedTaskImpl_AddDependency(t *testing.T) {
+	t.Run("When called this func at twice.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.AddDependency("lint")
+		task.AddDependency("test")
+
+		expected := []string{"lint", "test"}
+		assert.Equal(expected, task.Dependencies())
+
+		expected2 := "[DEBUG][15:04:05]   Add Dependency: lint\n[DEBUG][15:04:05]   Add Dependency: test\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
+func TestDefinedTaskImpl_AddEnv(t *testing.T) {
+	t.Run("When called this func at twice.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.AddEnv("FOO", "foo")
+		task.AddEnv("BAR", "")
+
+		expected := []string{"FOO=foo", "BAR="}
+		assert.Equal(expected, task.Env())
+
+		expected2 := "[DEBUG][15:04:05]   Add Env: FOO=foo\n[DEBUG][15:04:05]   Add Env: BAR=\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+
+	t.Run("When the key is already added.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.AddEnv("FOO", "foo")
+		task.AddEnv("FOO", "bar")
+
+		expected := []string{"FOO=bar"}
+		assert.Equal(expected, task.Env())
+	})
+}
+
+func TestDefinedTaskImpl_SetDir(t *testing.T) {
+	t.Run("When called this func.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.SetDir("./fixtures")
+
+		expected := "./fixtures"
+		assert.Equal(expected, task.Dir())
+
+		expected2 := "[DEBUG][15:04:05]   Set Dir: ./fixtures\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
+func TestDefinedTaskImpl_WorkingDir(t *testing.T) {
+	t.Run("When dir is not set.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+		task.SetRootDir("/path/to/project")
+
+		expected := "/path/to/project"
+		assert.Equal(expected, task.WorkingDir())
+	})
+
+	t.Run("When dir is relative.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{dir: "./fixtures"}
+		task.SetRootDir("/path/to/project")
+
+		expected := "/path/to/project/fixtures"
+		assert.Equal(expected, task.WorkingDir())
+	})
+
+	t.Run("When dir is absolute.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{dir: "/tmp"}
+		task.SetRootDir("/path/to/project")
+
+		expected := "/tmp"
+		assert.Equal(expected, task.WorkingDir())
+	})
+}
+
+func TestDefinedTaskImpl_AddCommandWithTimeout(t *testing.T) {
+	t.Run("When called this func with and without timeout.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.AddCommandWithTimeout("echo foo", 0)
+		task.AddCommandWithTimeout("echo bar", 5*time.Second)
+
+		expected := []string{"echo foo", "echo bar"}
+		assert.Equal(expected, task.Commands())
+
+		expected2 := map[int]time.Duration{1: 5 * time.Second}
+		assert.Equal(expected2, task.commandTimeouts)
+
+		expected3 := "[DEBUG][15:04:05]   Add Command: echo foo\n" +
+			"[DEBUG][15:04:05]   Add Command: echo bar\n" +
+			"[DEBUG][15:04:05]   Set Command Timeout: 5s\n"
+		assert.Equal(expected3, iobuffer.String())
+	})
+}
+
+func TestDefinedTaskImpl_SetTimeout(t *testing.T) {
+	t.Run("When called this func.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{}
+
+		task.SetTimeout(time.Minute)
+
+		expected := time.Minute
+		assert.Equal(expected, task.Timeout())
+
+		expected2 := "[DEBUG][15:04:05]   Set Timeout: 1m0s\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
+func TestFindTaskByName(t *testing.T) {
+	build := &DefinedTaskImpl{name: "build", aliases: []string{"b", "compile"}}
+	compile := &DefinedTaskImpl{name: "compile"}
+	tasks := []DefinedTask{build, compile}
+
+	t.Run("When the name of a task is given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, found := findTaskByName(tasks, "compile")
+
+		assert.True(found)
+		assert.Equal(compile, actual)
+	})
+
+	t.Run("When the alias of a task is given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, found := findTaskByName(tasks, "b")
+
+		assert.True(found)
+		assert.Equal(build, actual)
+	})
+
+	t.Run("When the task is not defined.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, found := findTaskByName(tasks, "test")
+
+		assert.False(found)
+	})
+}
+
 func TestDefinedTaskImpl_Run(t *testing.T) {
 	var executor *MockExecutor
-	NewExecutor = func(dryRun bool, command string, args []string) Executor {
+	NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
 		return executor
 	}
 
@@ -190,9 +537,9 @@ func TestDefinedTaskImpl_Run(t *testing.T) {
 		args := []string{"bar"}
 		executor = new(MockExecutor)
 
-		executor.On("Execute").Return(fmt.Errorf("mock return"))
+		executor.On("Execute", mock.Anything).Return(fmt.Errorf("mock return"))
 
-		actual := task.Run(dryRun, args)
+		actual := task.Run(context.Background(), dryRun, args, &interleavedOutput{})
 
 		assert.Error(actual)
 
@@ -200,6 +547,242 @@ func TestDefinedTaskImpl_Run(t *testing.T) {
 		assert.Equal(expect, iobuffer.String())
 	})
 
+	t.Run("When commands have template variables.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var commands []string
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			commands = append(commands, command)
+			return executorFunc(func(ctx context.Context) error {
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:     "build",
+			rootDir:  "/path/to/project",
+			commands: []string{"go build -o {{.BIN_DIR}}/app {{.CLI_ARGS}}", "echo {{.TASK}} {{.OS}}/{{.ARCH}}"},
+			vars:     []Var{{Name: "BIN_DIR", Value: "{{.ROOT_DIR}}/bin"}},
+		}
+
+		actual := task.Run(context.Background(), false, []string{"-v", "./..."}, &interleavedOutput{})
+
+		assert.NoError(actual)
+
+		expected := []string{
+			"go build -o /path/to/project/bin/app -v ./...",
+			"echo build " + runtime.GOOS + "/" + runtime.GOARCH,
+		}
+		assert.Equal(expected, commands)
+	})
+
+	t.Run("When vars refer to overridden variables.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var commands []string
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			commands = append(commands, command)
+			return executorFunc(func(ctx context.Context) error {
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:     "deploy",
+			commands: []string{"echo {{.URL}} {{.REGION}}"},
+			vars: []Var{
+				{Name: "URL", Value: "https://{{.ENV}}.example.com"},
+				{Name: "REGION", Sh: "exit 1"},
+			},
+		}
+		task.OverrideVar("ENV", "dev")
+		task.OverrideVar("REGION", "eu")
+
+		actual := task.Run(context.Background(), false, nil, &interleavedOutput{})
+
+		assert.NoError(actual)
+
+		expected := []string{"echo https://dev.example.com eu"}
+		assert.Equal(expected, commands)
+	})
+
+	t.Run("When commands have undefined template variables.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		executed := false
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			executed = true
+			return executorFunc(func(ctx context.Context) error {
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:     "build",
+			commands: []string{"echo build", "go build -o {{.BIN_DIR}}/app"},
+		}
+
+		actual := task.Run(context.Background(), false, nil, &interleavedOutput{})
+
+		assert.Error(actual)
+		assert.False(executed)
+
+		expected := "[ERROR][15:04:05] Failed to render task. task: build, error: template: build:1:"
+		assert.Contains(iobuffer.String(), expected)
+
+		expected2 := "map has no entry for key \"BIN_DIR\""
+		assert.Contains(iobuffer.String(), expected2)
+	})
+
+	t.Run("When task has sh vars.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		shVars = newShVarCache()
+		defer func() {
+			shVars = newShVarCache()
+		}()
+
+		var executed []string
+		var dirs []string
+		var envs [][]string
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			executed = append(executed, command)
+			dirs = append(dirs, dir)
+			envs = append(envs, env)
+			return executorFunc(func(ctx context.Context) error {
+				if command == "git rev-parse --short HEAD" {
+					fmt.Fprintln(output.Stdout(), "abc1234")
+				}
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:     "build",
+			rootDir:  "/path/to/project",
+			dir:      "build/{{.GIT_SHA}}",
+			commands: []string{"echo {{.GIT_SHA}}", "echo {{.GIT_SHA}}"},
+			env:      []string{"REVISION={{.GIT_SHA}}"},
+			vars: []Var{
+				{Name: "GIT_SHA", Sh: "git rev-parse --short HEAD"},
+				{Name: "UNUSED", Sh: "exit 1"},
+			},
+		}
+
+		actual := task.Run(context.Background(), false, nil, &interleavedOutput{})
+
+		assert.NoError(actual)
+
+		expected := []string{"git rev-parse --short HEAD", "echo abc1234", "echo abc1234"}
+		assert.Equal(expected, executed)
+
+		expected2 := []string{"/path/to/project", "/path/to/project/build/abc1234", "/path/to/project/build/abc1234"}
+		assert.Equal(expected2, dirs)
+
+		expected3 := []string{"REVISION=abc1234"}
+		assert.Equal(expected3, envs[1])
+	})
+
+	t.Run("When commands refer to sh vars in a dry run.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		shVars = newShVarCache()
+		defer func() {
+			shVars = newShVarCache()
+		}()
+
+		var executed []string
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			executed = append(executed, command)
+			assert.True(dryRun)
+			return executorFunc(func(ctx context.Context) error {
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:     "build",
+			commands: []string{"echo {{.GIT_SHA}}"},
+			vars: []Var{
+				{Name: "GIT_SHA", Sh: "touch SIDE_EFFECT"},
+			},
+		}
+
+		actual := task.Run(context.Background(), true, nil, &interleavedOutput{})
+
+		assert.NoError(actual)
+
+		expected := []string{"echo $(touch SIDE_EFFECT)"}
+		assert.Equal(expected, executed)
+		assert.Contains(iobuffer.String(), "Evaluate var. sh: \"touch SIDE_EFFECT\"")
+	})
+
+	t.Run("When task has timeout.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var timeouts []time.Duration
+		var deadlines []time.Time
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			timeouts = append(timeouts, timeout)
+			return executorFunc(func(ctx context.Context) error {
+				deadline, _ := ctx.Deadline()
+				deadlines = append(deadlines, deadline)
+				return nil
+			})
+		}
+
+		task := DefinedTaskImpl{
+			name:            "foo",
+			commands:        []string{"echo foo", "echo bar"},
+			timeout:         time.Hour,
+			commandTimeouts: map[int]time.Duration{1: time.Minute},
+		}
+
+		actual := task.Run(context.Background(), false, nil, &interleavedOutput{})
+
+		assert.NoError(actual)
+
+		expected := []time.Duration{0, time.Minute}
+		assert.Equal(expected, timeouts)
+
+		assert.Len(deadlines, 2)
+		assert.True(time.Until(deadlines[0]) <= time.Hour)
+		assert.True(time.Until(deadlines[0]) > time.Minute)
+
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			return executor
+		}
+	})
+
+	t.Run("When task timed out.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		task := DefinedTaskImpl{
+			name:     "foo",
+			commands: []string{"echo foo"},
+		}
+		executor = new(MockExecutor)
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		actual := task.Run(ctx, false, nil, &interleavedOutput{})
+
+		_, ok := actual.(*TimeoutError)
+		assert.True(ok)
+
+		executor.AssertNotCalled(t, "Execute", mock.Anything)
+
+		expected := "[INFO][15:04:05] Execute task: foo\n[ERROR][15:04:05] command timed out: echo foo\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
 	t.Run("When no error occured in runOnce.", func(t *testing.T) {
 		iobuffer.Reset()
 
@@ -216,9 +799,9 @@ func TestDefinedTaskImpl_Run(t *testing.T) {
 		args := []string{"bar"}
 		executor = new(MockExecutor)
 
-		executor.On("Execute").Return(nil)
+		executor.On("Execute", mock.Anything).Return(nil)
 
-		actual := task.Run(dryRun, args)
+		actual := task.Run(context.Background(), dryRun, args, &interleavedOutput{})
 
 		assert.NoError(actual)
 
@@ -229,7 +812,7 @@ func TestDefinedTaskImpl_Run(t *testing.T) {
 
 func TestDefinedTaskImpl_runOnce(t *testing.T) {
 	var executor *MockExecutor
-	NewExecutor = func(dryRun bool, command string, args []string) Executor {
+	NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
 		return executor
 	}
 
@@ -244,9 +827,9 @@ func TestDefinedTaskImpl_runOnce(t *testing.T) {
 		args := []string{"bar"}
 		executor = new(MockExecutor)
 
-		executor.On("Execute").Return(fmt.Errorf("mock return"))
+		executor.On("Execute", mock.Anything).Return(fmt.Errorf("mock return"))
 
-		actual := task.runOnce(dryRun, command, args)
+		actual := task.runOnce(context.Background(), dryRun, command, args, &interleavedOutput{}, 0)
 
 		assert.Error(actual)
 
@@ -265,9 +848,9 @@ func TestDefinedTaskImpl_runOnce(t *testing.T) {
 		args := []string{"bar"}
 		executor = new(MockExecutor)
 
-		executor.On("Execute").Return("", nil)
+		executor.On("Execute", mock.Anything).Return("", nil)
 
-		actual := task.runOnce(dryRun, command, args)
+		actual := task.runOnce(context.Background(), dryRun, command, args, &interleavedOutput{}, 0)
 
 		assert.Nil(actual)
 
@@ -1,5 +1,151 @@
-// +build !debug
-
 package main
 
-const DEBUG = false
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} in the value with the variable in env, or in the environment of the process.
+// An undefined variable is replaced with an empty string.
+func expandEnv(value string, env []string) string {
+	return envReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		key := envReferencePattern.FindStringSubmatch(reference)[1]
+		for i := len(env) - 1; i >= 0; i-- {
+			if strings.HasPrefix(env[i], key+"=") {
+				return strings.TrimPrefix(env[i], key+"=")
+			}
+		}
+		return os.Getenv(key)
+	})
+}
+
+func setEnv(env []string, key string, value string) []string {
+	for i, variable := range env {
+		if strings.HasPrefix(variable, key+"=") {
+			env[i] = key + "=" + value
+			return env
+		}
+	}
+	return append(env, key+"="+value)
+}
+
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var dotenvCommentPattern = regexp.MustCompile(`\s+#.*$`)
+
+// loadDotenvFiles reads the dotenv files in order, and returns their variables as KEY=VALUE.
+// The paths are relative to dir, and files which do not exist are skipped.
+// References in the values are expanded with env and the variables read before.
+func loadDotenvFiles(dir string, paths []string, env []string) ([]string, error) {
+	var variables []string
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		buf, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			Debug("Skipped dotenv file because it does not exist. path: %s", path)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		scope := append(append([]string{}, env...), variables...)
+		fileVariables, err := parseDotenv(path, string(buf), scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, variable := range fileVariables {
+			pair := strings.SplitN(variable, "=", 2)
+			variables = setEnv(variables, pair[0], pair[1])
+		}
+	}
+	return variables, nil
+}
+
+// parseDotenv parses the content of a dotenv file, and returns its variables as KEY=VALUE.
+//
+// Lines are KEY=VALUE, optionally prefixed with export, and lines starting with # are comments.
+// Double-quoted values may contain escape sequences and span multiple lines,
+// single-quoted values are taken literally, and unquoted values end at a comment.
+// ${VAR} in double-quoted and unquoted values is expanded.
+func parseDotenv(path string, buf string, env []string) ([]string, error) {
+	var variables []string
+	lines := strings.Split(strings.Replace(buf, "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export"):])
+		}
+
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("invalid line in dotenv file. path: %s, line: %d", path, lineNumber)
+		}
+		key := strings.TrimSpace(line[:separator])
+		if !dotenvKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid key in dotenv file. path: %s, line: %d", path, lineNumber)
+		}
+
+		value := strings.TrimSpace(line[separator+1:])
+		literal := strings.HasPrefix(value, "'")
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			quote := value[:1]
+			quoted := value[1:]
+			for {
+				if end := closingQuote(quoted, quote); end >= 0 {
+					value = quoted[:end]
+					break
+				}
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("unterminated quoted value in dotenv file. path: %s, line: %d", path, lineNumber)
+				}
+				quoted += "\n" + strings.TrimSuffix(lines[i], "\r")
+			}
+			if quote == `"` {
+				value = unescapeDotenvValue(value)
+			}
+		} else {
+			value = dotenvCommentPattern.ReplaceAllString(value, "")
+		}
+
+		if !literal {
+			scope := append(append([]string{}, env...), variables...)
+			value = expandEnv(value, scope)
+		}
+		variables = setEnv(variables, key, value)
+	}
+	return variables, nil
+}
+
+// closingQuote returns the index of the quote which closes the value, or -1.
+// In double-quoted values, quotes escaped with a backslash are skipped.
+func closingQuote(value string, quote string) int {
+	for i := 0; i < len(value); i++ {
+		if quote == `"` && value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == quote[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+var dotenvEscapeReplacer = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescapeDotenvValue(value string) string {
+	return dotenvEscapeReplacer.Replace(value)
+}
@@ -1,5 +0,0 @@
-// +build debug
-
-package main
-
-const DEBUG = true
@@ -0,0 +1,122 @@
+package main
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("TASKAL_TEST_OUTER", "outer")
+	defer os.Unsetenv("TASKAL_TEST_OUTER")
+
+	t.Run("When referencing the variable in env.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := expandEnv("${FOO}/bin", []string{"FOO=/usr", "FOO=/usr/local"})
+
+		expected := "/usr/local/bin"
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When referencing the variable of the process.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := expandEnv("${TASKAL_TEST_OUTER}:${TASKAL_TEST_UNDEFINED}", nil)
+
+		expected := "outer:"
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the value has no braces.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := expandEnv("$TASKAL_TEST_OUTER $$", nil)
+
+		expected := "$TASKAL_TEST_OUTER $$"
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestSetEnv(t *testing.T) {
+	t.Run("When the key is new.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := setEnv([]string{"FOO=foo"}, "BAR", "bar")
+
+		expected := []string{"FOO=foo", "BAR=bar"}
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the key exists.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := setEnv([]string{"FOO=foo", "FOOBAR=foobar"}, "FOO", "bar")
+
+		expected := []string{"FOO=bar", "FOOBAR=foobar"}
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestParseDotenv(t *testing.T) {
+	t.Run("When the values are quoted.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "DOUBLE=\"foo\\tbar\\n\\\"${NAME}\\\"\" # comment\n" +
+			"SINGLE='foo\\tbar ${NAME}'\n" +
+			"MULTI=\"foo\nbar\"\n"
+		actual, err := parseDotenv(".env", buf, []string{"NAME=taskal"})
+
+		assert.NoError(err)
+
+		expected := []string{
+			"DOUBLE=foo\tbar\n\"taskal\"",
+			"SINGLE=foo\\tbar ${NAME}",
+			"MULTI=foo\nbar",
+		}
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the values are not quoted.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "# comment\n\nexport FOO = foo # comment\nBAR=${FOO}#bar\nBAZ=\r\n"
+		actual, err := parseDotenv(".env", buf, nil)
+
+		assert.NoError(err)
+
+		expected := []string{
+			"FOO=foo",
+			"BAR=foo#bar",
+			"BAZ=",
+		}
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the line has no separator.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := parseDotenv(".env", "FOO=foo\nBAR\n", nil)
+
+		expected := "invalid line in dotenv file. path: .env, line: 2"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the key is invalid.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := parseDotenv(".env", "FOO-BAR=foo\n", nil)
+
+		expected := "invalid key in dotenv file. path: .env, line: 1"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the quote is not closed.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := parseDotenv(".env", "FOO=\"foo\nBAR=bar\n", nil)
+
+		expected := "unterminated quoted value in dotenv file. path: .env, line: 1"
+		assert.EqualError(err, expected)
+	})
+}
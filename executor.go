@@ -1,40 +1,63 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/fatih/color"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 )
 
 type Executor interface {
-	Execute() error
+	Execute(context.Context) error
 }
 
 type ExecutorImpl struct {
 	dryRun  bool
 	command string
 	args    []string
+	dir     string
+	env     []string
+	output  Output
+	timeout time.Duration
 }
 
-var NewExecutor = func(dryRun bool, command string, args []string) Executor {
-	return &ExecutorImpl{dryRun, command, args}
+var NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+	return &ExecutorImpl{dryRun, command, args, dir, env, output, timeout}
 }
 
-func (e *ExecutorImpl) Execute() error {
+func (e *ExecutorImpl) Execute(ctx context.Context) error {
+	parent := ctx
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	var err error
 	if runtime.GOOS == "windows" {
-		return e.execOnWindows()
+		err = e.execOnWindows(ctx)
 	} else {
-		return e.execOnUnix()
+		err = e.execOnUnix(ctx)
+	}
+
+	// The timeout of the task or the whole run expired rather than the timeout of the command.
+	if timeoutErr, ok := err.(*TimeoutError); ok && parent.Err() != nil {
+		timeoutErr.Timeout = 0
 	}
+	return err
 }
 
-func (e *ExecutorImpl) execOnWindows() error {
-	Info(color.HiBlackString("exec %s", QuoteString(e.command)))
-	return e.execCommand("exec", e.command)
+func (e *ExecutorImpl) execOnWindows(ctx context.Context) error {
+	InfoTo(e.output, color.HiBlackString("exec %s", QuoteString(e.command)))
+	return e.execCommand(ctx, "exec", e.command)
 }
 
-func (e *ExecutorImpl) execOnUnix() error {
+func (e *ExecutorImpl) execOnUnix(ctx context.Context) error {
 	execArgs := []string{
 		"-c",
 		e.command,
@@ -44,25 +67,173 @@ func (e *ExecutorImpl) execOnUnix() error {
 		execArgs = append(execArgs, "--")
 		execArgs = append(execArgs, e.args...)
 
-		Info(color.HiBlackString("sh -c %s -- %s", QuoteString(e.command), strings.Join(e.args, " ")))
+		InfoTo(e.output, color.HiBlackString("sh -c %s -- %s", QuoteString(e.command), strings.Join(e.args, " ")))
 	} else {
-		Info(color.HiBlackString("sh -c %s", QuoteString(e.command)))
+		InfoTo(e.output, color.HiBlackString("sh -c %s", QuoteString(e.command)))
 	}
 
-	return e.execCommand("sh", execArgs...)
+	return e.execCommand(ctx, "sh", execArgs...)
 }
 
-func (e ExecutorImpl) execCommand(name string, args ...string) error {
+func (e ExecutorImpl) execCommand(ctx context.Context, name string, args ...string) error {
 	if !e.dryRun {
-		return doExecCommand(name, args...)
+		err := doExecCommand(ctx, e.newCommand(name, args...))
+		if err == context.DeadlineExceeded {
+			return &TimeoutError{Command: e.command, Timeout: e.timeout}
+		}
+		return newExitError(e.command, err)
 	} else {
 		return nil
 	}
 }
 
-var doExecCommand = func(name string, args ...string) error {
+func (e ExecutorImpl) newCommand(name string, args ...string) *exec.Cmd {
 	cmd := exec.Command(name, args...)
-	cmd.Stdout = Stdout
-	cmd.Stderr = Stderr
-	return cmd.Run()
+	if e.dir != "" {
+		Trace("  Working directory: %s", e.dir)
+		cmd.Dir = e.dir
+	}
+	if len(e.env) > 0 {
+		Trace("  Environment: %s", strings.Join(e.env, " "))
+		cmd.Env = append(os.Environ(), e.env...)
+	}
+	cmd.Stdout = e.output.Stdout()
+	cmd.Stderr = e.output.Stderr()
+	setProcessGroup(cmd)
+	return cmd
+}
+
+// TerminateGracePeriod is how long to wait for the processes to exit before killing them.
+var TerminateGracePeriod = 5 * time.Second
+
+var doExecCommand = runCommand
+
+// runCommand runs the command, and terminates its process group when the context is done.
+// While the command is running, received signals are forwarded to its process group.
+// The processes which do not exit within TerminateGracePeriod after a signal, or when a signal is received again,
+// are killed, including the ones left in the background after the command exits.
+//
+// exec.CommandContext is not used because it kills only the started process,
+// and processes started by the command would be left.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	runningCommands.Add(cmd)
+	defer runningCommands.Remove(cmd)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if runningCommands.Signal() != nil {
+			// The command exited by the forwarded signal, but processes in the background may ignore it.
+			timer := time.NewTimer(TerminateGracePeriod)
+			defer timer.Stop()
+			sweepProcessGroup(cmd, timer.C, runningCommands.Repeated())
+		}
+		return err
+	case <-ctx.Done():
+		if runningCommands.Signal() != nil {
+			// The received signal has been forwarded to the command, so let it shut down by itself.
+			return waitProcessGroup(cmd, done, runningCommands.Repeated())
+		}
+		signalProcessGroup(cmd, syscall.SIGTERM)
+		waitProcessGroup(cmd, done, nil)
+		return ctx.Err()
+	}
+}
+
+// waitProcessGroup waits for the command and the other processes in its process group to exit,
+// and kills the remaining processes after TerminateGracePeriod or when stop is closed.
+func waitProcessGroup(cmd *exec.Cmd, done <-chan error, stop <-chan struct{}) error {
+	timer := time.NewTimer(TerminateGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		sweepProcessGroup(cmd, timer.C, stop)
+		return err
+	case <-timer.C:
+	case <-stop:
+	}
+	signalProcessGroup(cmd, syscall.SIGKILL)
+	return <-done
+}
+
+// sweepProcessGroup sends SIGTERM to the processes left in the process group of the exited command,
+// and kills them if they do not exit until expired or stop is closed.
+func sweepProcessGroup(cmd *exec.Cmd, expired <-chan time.Time, stop <-chan struct{}) {
+	if !processGroupExists(cmd) {
+		return
+	}
+	signalProcessGroup(cmd, syscall.SIGTERM)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for processGroupExists(cmd) {
+		select {
+		case <-ticker.C:
+		case <-expired:
+			signalProcessGroup(cmd, syscall.SIGKILL)
+			return
+		case <-stop:
+			signalProcessGroup(cmd, syscall.SIGKILL)
+			return
+		}
+	}
+}
+
+// ExitError reports that a command exited with a non-zero status.
+type ExitError struct {
+	Command string
+	Code    int
+	err     error
+}
+
+func (e *ExitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+func newExitError(command string, err error) error {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	code := 1
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+		if status.Signaled() {
+			code = 128 + int(status.Signal())
+		} else {
+			code = status.ExitStatus()
+		}
+	}
+	return &ExitError{Command: command, Code: code, err: err}
+}
+
+// TimeoutError reports that a command was terminated because its timeout expired.
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("command timed out after %s: %s", e.Timeout.Round(time.Millisecond), e.Command)
+	}
+	return fmt.Sprintf("command timed out: %s", e.Command)
+}
+
+func (e *TimeoutError) ExitCode() int {
+	return TimedOut
 }
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	assert2 "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"os/exec"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 type MockExecutor struct {
 	mock.Mock
 }
 
-func (m *MockExecutor) Execute() error {
-	ret := m.Called().Get(0)
+func (m *MockExecutor) Execute(ctx context.Context) error {
+	ret := m.Called(ctx).Get(0)
 
 	if v, ok := ret.(error); ok {
 		return v
@@ -23,7 +29,7 @@ func (m *MockExecutor) Execute() error {
 
 func TestExecutorImpl_Execute(t *testing.T) {
 	t.Run("When an error occurred.", func(t *testing.T) {
-		doExecCommand = func(name string, args ...string) error {
+		doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
 			return fmt.Errorf("error message")
 		}
 
@@ -32,19 +38,20 @@ func TestExecutorImpl_Execute(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args: []string{
 				"bar",
 				"buz",
 			},
 		}
 
-		actual := executor.Execute()
+		actual := executor.Execute(context.Background())
 
 		assert.Error(actual)
 	})
 
 	t.Run("When no error occurred.", func(t *testing.T) {
-		doExecCommand = func(name string, args ...string) error {
+		doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
 			return nil
 		}
 
@@ -53,13 +60,14 @@ func TestExecutorImpl_Execute(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args: []string{
 				"bar",
 				"buz",
 			},
 		}
 
-		actual := executor.Execute()
+		actual := executor.Execute(context.Background())
 
 		assert.NoError(actual)
 	})
@@ -68,9 +76,9 @@ func TestExecutorImpl_Execute(t *testing.T) {
 func TestExecutorImpl_execOnWindows(t *testing.T) {
 	var execName string
 	var execArgs []string
-	doExecCommand = func(name string, args ...string) error {
-		execName = name
-		execArgs = args
+	doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
+		execName = cmd.Args[0]
+		execArgs = cmd.Args[1:]
 		return fmt.Errorf("error message")
 	}
 
@@ -84,10 +92,11 @@ func TestExecutorImpl_execOnWindows(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args:    []string{},
 		}
 
-		actual := executor.execOnWindows()
+		actual := executor.execOnWindows(context.Background())
 
 		assert.Error(actual)
 
@@ -113,13 +122,14 @@ func TestExecutorImpl_execOnWindows(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args: []string{
 				"bar",
 				"baz",
 			},
 		}
 
-		actual := executor.execOnWindows()
+		actual := executor.execOnWindows(context.Background())
 
 		assert.Error(actual)
 
@@ -139,9 +149,9 @@ func TestExecutorImpl_execOnWindows(t *testing.T) {
 func TestExecutorImpl_execOnUnix(t *testing.T) {
 	var execName string
 	var execArgs []string
-	doExecCommand = func(name string, args ...string) error {
-		execName = name
-		execArgs = args
+	doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
+		execName = cmd.Args[0]
+		execArgs = cmd.Args[1:]
 		return fmt.Errorf("error message")
 	}
 
@@ -155,10 +165,11 @@ func TestExecutorImpl_execOnUnix(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args:    []string{},
 		}
 
-		actual := executor.execOnUnix()
+		actual := executor.execOnUnix(context.Background())
 
 		assert.Error(actual)
 
@@ -185,13 +196,14 @@ func TestExecutorImpl_execOnUnix(t *testing.T) {
 		executor := ExecutorImpl{
 			dryRun:  false,
 			command: "echo foo",
+			output:  &interleavedOutput{},
 			args: []string{
 				"bar",
 				"baz",
 			},
 		}
 
-		actual := executor.execOnUnix()
+		actual := executor.execOnUnix(context.Background())
 
 		assert.Error(actual)
 
@@ -213,7 +225,7 @@ func TestExecutorImpl_execOnUnix(t *testing.T) {
 }
 
 func TestExecutorImpl_execCommand(t *testing.T) {
-	doExecCommand = func(name string, args ...string) error {
+	doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
 		return fmt.Errorf("error message")
 	}
 
@@ -222,6 +234,7 @@ func TestExecutorImpl_execCommand(t *testing.T) {
 
 		executor := ExecutorImpl{
 			dryRun: false,
+			output: &interleavedOutput{},
 		}
 		name := "foo"
 		args := []string{
@@ -229,7 +242,7 @@ func TestExecutorImpl_execCommand(t *testing.T) {
 			"baz",
 		}
 
-		actual := executor.execCommand(name, args...)
+		actual := executor.execCommand(context.Background(), name, args...)
 
 		assert.Error(actual)
 	})
@@ -246,8 +259,293 @@ func TestExecutorImpl_execCommand(t *testing.T) {
 			"baz",
 		}
 
-		actual := executor.execCommand(name, args...)
+		actual := executor.execCommand(context.Background(), name, args...)
 
 		assert.NoError(actual)
 	})
 }
+
+func TestExecutorImpl_execCommand_timeout(t *testing.T) {
+	t.Run("When the command timed out.", func(t *testing.T) {
+		doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
+			return context.DeadlineExceeded
+		}
+
+		assert := assert2.New(t)
+
+		executor := ExecutorImpl{
+			command: "sleep 10",
+			output:  &interleavedOutput{},
+			timeout: time.Second,
+		}
+
+		actual := executor.execCommand(context.Background(), "sh", "-c", "sleep 10")
+
+		expected := "command timed out after 1s: sleep 10"
+		assert.EqualError(actual, expected)
+
+		timeoutErr, ok := actual.(*TimeoutError)
+		assert.True(ok)
+
+		expected2 := TimedOut
+		assert.Equal(expected2, timeoutErr.ExitCode())
+	})
+}
+
+func TestExecutorImpl_Execute_timeout(t *testing.T) {
+	doExecCommand = func(ctx context.Context, cmd *exec.Cmd) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("When the timeout of the command expired.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		executor := ExecutorImpl{
+			command: "sleep 10",
+			output:  &interleavedOutput{},
+			timeout: 10 * time.Millisecond,
+		}
+
+		actual := executor.Execute(context.Background())
+
+		expected := "command timed out after 10ms: sleep 10"
+		assert.EqualError(actual, expected)
+	})
+
+	t.Run("When the timeout of the task expired.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		executor := ExecutorImpl{
+			command: "sleep 10",
+			output:  &interleavedOutput{},
+			timeout: time.Minute,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		actual := executor.Execute(ctx)
+
+		expected := "command timed out: sleep 10"
+		assert.EqualError(actual, expected)
+	})
+}
+
+func TestRunCommand(t *testing.T) {
+	originTerminateGracePeriod := TerminateGracePeriod
+
+	defer func() {
+		TerminateGracePeriod = originTerminateGracePeriod
+	}()
+
+	TerminateGracePeriod = 100 * time.Millisecond
+
+	t.Run("When the command completed within timeout.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		cmd := exec.Command("sh", "-c", "exit 2")
+		setProcessGroup(cmd)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		actual := runCommand(ctx, cmd)
+
+		expected := "exit status 2"
+		assert.EqualError(actual, expected)
+	})
+
+	t.Run("When the command timed out.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		cmd := exec.Command("sh", "-c", "sleep 10 & wait")
+		setProcessGroup(cmd)
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		actual := runCommand(ctx, cmd)
+
+		assert.Equal(context.DeadlineExceeded, actual)
+		assert.True(time.Since(start) < 5*time.Second)
+	})
+
+	t.Run("When the command ignores SIGTERM.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		cmd := exec.Command("sh", "-c", "trap '' TERM; sleep 10")
+		setProcessGroup(cmd)
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		actual := runCommand(ctx, cmd)
+
+		assert.Equal(context.DeadlineExceeded, actual)
+		assert.True(time.Since(start) < 5*time.Second)
+	})
+
+	interrupt := func(ctx context.Context, cmd *exec.Cmd, signals int) error {
+		originRunningCommands := runningCommands
+		defer func() {
+			runningCommands = originRunningCommands
+		}()
+		runningCommands = newCommandRegistry()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			for i := 0; i < signals; i++ {
+				runningCommands.Interrupt(syscall.SIGINT)
+				cancel()
+			}
+		}()
+		return runCommand(ctx, cmd)
+	}
+
+	t.Run("When the command ignores the signal.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		cmd := exec.Command("sh", "-c", "trap '' INT; sleep 10")
+		setProcessGroup(cmd)
+
+		start := time.Now()
+		actual := interrupt(context.Background(), cmd, 1)
+
+		assert.EqualError(actual, "signal: killed")
+		assert.True(time.Since(start) < 5*time.Second)
+	})
+
+	t.Run("When a process in the background ignores the signal.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var stdout bytes.Buffer
+		cmd := exec.Command("sh", "-c", "sleep 10 & echo $!; sleep 10")
+		cmd.Stdout = &stdout
+		setProcessGroup(cmd)
+
+		start := time.Now()
+		actual := interrupt(context.Background(), cmd, 1)
+
+		assert.Error(actual)
+		assert.True(time.Since(start) < 5*time.Second)
+
+		// The background process is gone, or is a zombie left to be reaped.
+		stat, _ := exec.Command("ps", "-o", "stat=", "-p", strings.TrimSpace(stdout.String())).Output()
+		assert.Regexp(`^Z?$`, strings.TrimSpace(string(stat)))
+	})
+
+	t.Run("When the signal is received again.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		TerminateGracePeriod = time.Minute
+		defer func() {
+			TerminateGracePeriod = 100 * time.Millisecond
+		}()
+
+		cmd := exec.Command("sh", "-c", "trap '' INT; sleep 10")
+		setProcessGroup(cmd)
+
+		start := time.Now()
+		actual := interrupt(context.Background(), cmd, 2)
+
+		assert.EqualError(actual, "signal: killed")
+		assert.True(time.Since(start) < 5*time.Second)
+	})
+}
+
+func TestExecutorImpl_newCommand(t *testing.T) {
+	t.Run("When dir and env are not specified.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		executor := ExecutorImpl{
+			output: &interleavedOutput{},
+		}
+
+		cmd := executor.newCommand("sh", "-c", "echo foo")
+
+		expected := []string{"sh", "-c", "echo foo"}
+		assert.Equal(expected, cmd.Args)
+
+		expected2 := ""
+		assert.Equal(expected2, cmd.Dir)
+
+		assert.Nil(cmd.Env)
+	})
+
+	t.Run("When dir and env are specified.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		executor := ExecutorImpl{
+			dir:    "fixtures",
+			env:    []string{"FOO=foo"},
+			output: &interleavedOutput{},
+		}
+
+		cmd := executor.newCommand("sh", "-c", "echo foo")
+
+		expected := "fixtures"
+		assert.Equal(expected, cmd.Dir)
+
+		expected2 := "FOO=foo"
+		assert.Equal(expected2, cmd.Env[len(cmd.Env)-1])
+	})
+}
+
+func TestNewExitError(t *testing.T) {
+	t.Run("When the command exited with status.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		err := exec.Command("sh", "-c", "exit 3").Run()
+
+		actual := newExitError("exit 3", err)
+
+		exitErr, ok := actual.(*ExitError)
+		assert.True(ok)
+
+		expected := 3
+		assert.Equal(expected, exitErr.ExitCode())
+
+		expected2 := "exit 3"
+		assert.Equal(expected2, exitErr.Command)
+
+		expected3 := "exit status 3"
+		assert.EqualError(actual, expected3)
+	})
+
+	t.Run("When the command was killed by signal.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		err := exec.Command("sh", "-c", "kill -TERM $$").Run()
+
+		actual := newExitError("kill -TERM $$", err)
+
+		exitErr, ok := actual.(*ExitError)
+		assert.True(ok)
+
+		expected := 143
+		assert.Equal(expected, exitErr.ExitCode())
+	})
+
+	t.Run("When the error is not exit error.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		err := fmt.Errorf("error message")
+
+		actual := newExitError("echo foo", err)
+
+		assert.Equal(err, actual)
+	})
+
+	t.Run("When no error occurred.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := newExitError("echo foo", nil)
+
+		assert.Nil(actual)
+	})
+}
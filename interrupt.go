@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// commandRegistry keeps the running commands so that received signals can be forwarded to them.
+type commandRegistry struct {
+	mutex    sync.Mutex
+	commands map[*exec.Cmd]bool
+	signal   os.Signal
+	// repeated is closed when a signal is received again.
+	repeated chan struct{}
+}
+
+var runningCommands = newCommandRegistry()
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{
+		commands: make(map[*exec.Cmd]bool),
+		repeated: make(chan struct{}),
+	}
+}
+
+// Add registers the started command.
+// If a signal has already been received, it is forwarded to the command at once.
+func (r *commandRegistry) Add(cmd *exec.Cmd) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.commands[cmd] = true
+	if r.signal != nil {
+		forwardSignal(cmd, r.signal)
+	}
+}
+
+func (r *commandRegistry) Remove(cmd *exec.Cmd) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.commands, cmd)
+}
+
+// Interrupt records the signal and forwards it to the process groups of all running commands.
+func (r *commandRegistry) Interrupt(sig os.Signal) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.signal != nil {
+		select {
+		case <-r.repeated:
+		default:
+			close(r.repeated)
+		}
+	}
+	r.signal = sig
+	for cmd := range r.commands {
+		forwardSignal(cmd, sig)
+	}
+}
+
+// Signal returns the received signal, or nil if no signal has been received.
+func (r *commandRegistry) Signal() os.Signal {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.signal
+}
+
+// Repeated returns the channel which is closed when a signal is received again.
+func (r *commandRegistry) Repeated() <-chan struct{} {
+	return r.repeated
+}
+
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {
+	if s, ok := sig.(syscall.Signal); ok {
+		signalProcessGroup(cmd, s)
+	}
+}
+
+// HandleSignals traps SIGINT, SIGTERM and SIGHUP, forwards them to the running commands
+// and cancels the context of the run. The returned func stops trapping.
+var HandleSignals = func(cancel context.CancelFunc) func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	go func() {
+		for sig := range ch {
+			Warn("Received signal, stopping tasks. signal: %s", sig)
+			runningCommands.Interrupt(sig)
+			cancel()
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(ch)
+	}
+}
+
+// InterruptedError reports that tasks were stopped by a signal.
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by signal: %s", e.Signal)
+}
+
+func (e *InterruptedError) ExitCode() int {
+	if s, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return FailedExecute
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommandRegistry(t *testing.T) {
+	t.Run("When a signal is received while the command is running.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		registry := newCommandRegistry()
+		cmd := exec.Command("sh", "-c", "sleep 10 & wait")
+		setProcessGroup(cmd)
+		assert.NoError(cmd.Start())
+
+		registry.Add(cmd)
+
+		assert.Nil(registry.Signal())
+
+		start := time.Now()
+		registry.Interrupt(syscall.SIGTERM)
+		err := cmd.Wait()
+		registry.Remove(cmd)
+
+		assert.Error(err)
+		assert.True(time.Since(start) < 5*time.Second)
+
+		expected := syscall.SIGTERM
+		assert.Equal(expected, registry.Signal())
+
+		assert.Len(registry.commands, 0)
+	})
+
+	t.Run("When a signal was received before the command started.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		registry := newCommandRegistry()
+		registry.Interrupt(syscall.SIGTERM)
+
+		cmd := exec.Command("sh", "-c", "sleep 10")
+		setProcessGroup(cmd)
+		assert.NoError(cmd.Start())
+
+		start := time.Now()
+		registry.Add(cmd)
+		err := cmd.Wait()
+		registry.Remove(cmd)
+
+		assert.Error(err)
+		assert.True(time.Since(start) < 5*time.Second)
+	})
+}
+
+func TestInterruptedError(t *testing.T) {
+	t.Run("When interrupted by SIGINT.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		err := &InterruptedError{Signal: syscall.SIGINT}
+
+		expected := "interrupted by signal: interrupt"
+		assert.EqualError(err, expected)
+
+		expected2 := 130
+		assert.Equal(expected2, err.ExitCode())
+	})
+}
+
+func TestInterruptedRun(t *testing.T) {
+	originRunningCommands := runningCommands
+
+	defer func() {
+		runningCommands = originRunningCommands
+	}()
+
+	t.Run("When a signal was received before running a task.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		runningCommands = newCommandRegistry()
+		runningCommands.Interrupt(syscall.SIGTERM)
+
+		executor := new(MockExecutor)
+		NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+			return executor
+		}
+
+		task := DefinedTaskImpl{
+			name:     "foo",
+			commands: []string{"echo foo"},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		actual := task.Run(ctx, false, nil, &interleavedOutput{})
+
+		expected := "interrupted by signal: terminated"
+		assert.EqualError(actual, expected)
+
+		executor.AssertNotCalled(t, "Execute", mock.Anything)
+	})
+
+	t.Run("When a signal was received while running tasks.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		runningCommands = newCommandRegistry()
+
+		option := new(MockOption)
+		config := new(MockConfig)
+		lint := new(MockDefinedTask)
+		test := new(MockDefinedTask)
+		runner := RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+
+		option.On("HasSpecifiedTasks").Return(true)
+		option.On("SpecifiedTasks").Return("lint", "test")
+		option.On("BeDryRun").Return(false)
+		option.On("TaskArgsOf", mock.Anything).Return()
+		option.On("Jobs").Return(1)
+		option.On("KeepsGoing").Return(true)
+		option.On("OutputMode").Return(OutputInterleaved)
+		option.On("Timeout").Return(time.Duration(0))
+		option.On("Variables").Return()
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		config.On("DefinedTasks").Return(lint, test)
+
+		lint.On("Name").Return("lint")
+		lint.On("Dependencies").Return()
+		lint.On("Params").Return()
+		lint.On("Aliases").Return()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lint.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			runningCommands.Interrupt(syscall.SIGINT)
+			cancel()
+		}).Return(fmt.Errorf("exit status 130"))
+		test.On("Name").Return("test")
+		test.On("Dependencies").Return()
+		test.On("Params").Return()
+		test.On("Aliases").Return()
+
+		actual := runner.Run(ctx)
+
+		interruptedErr, ok := actual.(*InterruptedError)
+		assert.True(ok)
+
+		expected := 130
+		assert.Equal(expected, interruptedErr.ExitCode())
+
+		test.AssertNotCalled(t, "Run", mock.Anything, false, []string(nil))
+	})
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found by LintConfig.
+type LintIssue struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Task     string `json:"task,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i *LintIssue) String() string {
+	message := fmt.Sprintf("%s: %s (%s)", i.Severity, i.Message, i.Rule)
+	return (&ConfigError{Path: i.Path, Line: i.Line, Column: i.Column, Message: message}).Error()
+}
+
+// builtinCommands are the names handled by taskal itself instead of a task.
+var builtinCommands = []string{"help", "lint"}
+
+var argsPattern = regexp.MustCompile(`\$[@*]|\$\{[@*]\}`)
+
+// unportableShellPatterns are the constructs which are not supported by POSIX sh,
+// which runs the commands.
+var unportableShellPatterns = []struct {
+	pattern *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`\[\[`), "[[ is not supported by sh, use [ instead"},
+	{regexp.MustCompile(`(^|[;&|({]\s*|\s)function\s+\w+`), "function keyword is not supported by sh, use name() instead"},
+	{regexp.MustCompile(`(^|[;&|({]\s*)source\s`), "source is not supported by sh, use . instead"},
+	{regexp.MustCompile(`&>`), "&> is not supported by sh, use >file 2>&1 instead"},
+	{regexp.MustCompile(`[<>]\(`), "process substitution is not supported by sh"},
+	{regexp.MustCompile(`\$'`), "$'...' quoting is not supported by sh"},
+	{regexp.MustCompile(`(^|[;&|({]\s*|\s)echo\s+-e\b`), "echo -e is not portable, use printf instead"},
+	{regexp.MustCompile(`(^|\s)[A-Za-z_][A-Za-z0-9_]*=\(`), "arrays are not supported by sh"},
+}
+
+// LintConfig analyzes the config file without running any task.
+var LintConfig = func(path string, buf string) []*LintIssue {
+	config := &ConfigImpl{path: path}
+	file := &configFile{config: config, path: path}
+	err := file.parse(buf)
+	duplicates := parseErrorIssues(path, config.duplicates)
+	if err != nil {
+		return append(duplicates, parseErrorIssues(path, err)...)
+	}
+	config.sortDefinedTasks()
+
+	linter := &configLinter{config: config, issues: duplicates}
+	linter.lintTasks()
+	linter.lintEntries(path, buf)
+
+	sort.SliceStable(linter.issues, func(i int, j int) bool {
+		a, b := linter.issues[i], linter.issues[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return linter.issues
+}
+
+func parseErrorIssues(path string, err error) []*LintIssue {
+	var issues []*LintIssue
+	switch err := err.(type) {
+	case ConfigErrors:
+		for _, configErr := range err {
+			issues = append(issues, &LintIssue{
+				Path:     configErr.Path,
+				Line:     configErr.Line,
+				Column:   configErr.Column,
+				Severity: LintError,
+				Rule:     "config",
+				Message:  configErr.Message,
+			})
+		}
+	case DuplicateTaskErrors:
+		for _, duplicate := range err {
+			issues = append(issues, &LintIssue{
+				Path:     duplicate.Path,
+				Line:     duplicate.Line,
+				Severity: LintError,
+				Rule:     "shadowed-name",
+				Task:     duplicate.Task,
+				Message:  duplicate.Message(),
+			})
+		}
+	default:
+		issues = append(issues, &LintIssue{
+			Path:     path,
+			Severity: LintError,
+			Rule:     "config",
+			Message:  err.Error(),
+		})
+	}
+	return issues
+}
+
+type configLinter struct {
+	config *ConfigImpl
+	issues []*LintIssue
+}
+
+func (l *configLinter) add(task DefinedTask, severity string, rule string, format string, a ...interface{}) {
+	l.issues = append(l.issues, &LintIssue{
+		Path:     task.SourcePath(),
+		Line:     task.SourceLine(),
+		Severity: severity,
+		Rule:     rule,
+		Task:     task.Name(),
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *configLinter) lintTasks() {
+	tasks := map[string]DefinedTask{}
+	dependents := map[string][]string{}
+	for _, task := range l.config.DefinedTasks() {
+		tasks[task.Name()] = task
+		for _, alias := range task.Aliases() {
+			tasks[alias] = task
+		}
+	}
+	for _, task := range l.config.DefinedTasks() {
+		for _, dependency := range task.Dependencies() {
+			if dependentTask, ok := tasks[dependency]; ok {
+				dependents[dependentTask.Name()] = append(dependents[dependentTask.Name()], task.Name())
+			}
+		}
+	}
+
+	sameCommands := map[string]string{}
+	for _, task := range l.config.DefinedTasks() {
+		name := task.Name()
+		commands := task.Commands()
+
+		// Hidden tasks are often used only as anchors of definitions.
+		if len(commands) == 0 && len(task.Dependencies()) == 0 && !task.Hidden() {
+			l.add(task, LintWarning, "empty-task", "task has no commands and no dependencies. task: %s", name)
+		}
+
+		for _, dependency := range task.Dependencies() {
+			if _, ok := tasks[dependency]; !ok {
+				l.add(task, LintError, "undefined-dependency", "dependent task is not defined. task: %s, dependency: %s", name, dependency)
+			}
+		}
+
+		for _, builtin := range builtinCommands {
+			if name == builtin {
+				l.add(task, LintWarning, "shadowed-name", "task shadows the builtin command. task: %s", name)
+			}
+		}
+
+		seen := map[string]bool{}
+		for _, command := range commands {
+			if seen[command] {
+				l.add(task, LintWarning, "duplicate-command", "command is repeated in the task. task: %s, command: %s", name, command)
+			}
+			seen[command] = true
+		}
+		if len(commands) > 0 {
+			key := strings.Join(commands, "\x00")
+			if other, ok := sameCommands[key]; ok {
+				l.add(task, LintWarning, "duplicate-command", "task has the same commands as another task. task: %s, other: %s", name, other)
+			} else {
+				sameCommands[key] = name
+			}
+		}
+
+		if len(dependents[name]) > 0 {
+			for _, command := range commands {
+				if argsPattern.MatchString(command) {
+					l.add(task, LintWarning, "unused-args", "task uses arguments but runs without them as a dependency. task: %s, dependency of: %s", name, strings.Join(dependents[name], ", "))
+					break
+				}
+			}
+		}
+
+		for _, command := range commands {
+			for _, unportable := range unportableShellPatterns {
+				if unportable.pattern.MatchString(command) {
+					l.add(task, LintWarning, "unportable-shell", "%s. task: %s, command: %s", unportable.message, name, command)
+				}
+			}
+		}
+	}
+}
+
+// reservedNames are the top-level keys of the settings, which are tasks only when they are not in the forms of the settings.
+var reservedNames = map[string]bool{"env": true, "vars": true, "dotenv": true, "include": true}
+
+var commandLikePattern = regexp.MustCompile(`\s`)
+
+// lintEntries lints the top-level entries of the config file and the included files.
+func (l *configLinter) lintEntries(path string, buf string) {
+	dependencies := map[string]bool{}
+	for _, task := range l.config.DefinedTasks() {
+		for _, dependency := range task.Dependencies() {
+			dependencies[dependency] = true
+		}
+	}
+	referenced := map[string]bool{}
+	for _, task := range l.config.DefinedTasks() {
+		for _, name := range append([]string{task.Name()}, task.Aliases()...) {
+			if dependencies[name] {
+				referenced[fmt.Sprintf("%s:%d", task.SourcePath(), task.SourceLine())] = true
+			}
+		}
+	}
+
+	files := map[string]string{path: buf}
+	for _, task := range l.config.DefinedTasks() {
+		if _, ok := files[task.SourcePath()]; ok || task.SourcePath() == "" {
+			continue
+		}
+		if buf, err := ioutil.ReadFile(task.SourcePath()); err == nil {
+			files[task.SourcePath()] = string(buf)
+		}
+	}
+
+	for path, buf := range files {
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(buf), &document); err != nil || len(document.Content) == 0 {
+			continue
+		}
+
+		keys, values := mapItems(resolve(document.Content[0]))
+		l.lintHiddenEntries(path, &document, keys, values, referenced)
+		l.lintReservedNames(path, keys, values)
+	}
+}
+
+// lintHiddenEntries reports the hidden entries starting with `_` which are never referenced
+// by an alias or a dependency.
+func (l *configLinter) lintHiddenEntries(path string, document *yaml.Node, keys []*yaml.Node, values []*yaml.Node, referenced map[string]bool) {
+	aliases := map[string]bool{}
+	collectAliases(document, aliases)
+
+	for i, key := range keys {
+		if !strings.HasPrefix(key.Value, "_") || isSetting(key.Value, values[i]) {
+			continue
+		}
+		if values[i].Anchor != "" && aliases[values[i].Anchor] {
+			continue
+		}
+		if referenced[fmt.Sprintf("%s:%d", path, key.Line)] {
+			continue
+		}
+		l.issues = append(l.issues, &LintIssue{
+			Path:     path,
+			Line:     key.Line,
+			Column:   key.Column,
+			Severity: LintWarning,
+			Rule:     "unused-hidden",
+			Task:     key.Value,
+			Message:  fmt.Sprintf("hidden task is never referenced. task: %s", key.Value),
+		})
+	}
+}
+
+// lintReservedNames reports the tasks named after the settings,
+// and the dotenv and include settings which look like commands of tasks.
+func (l *configLinter) lintReservedNames(path string, keys []*yaml.Node, values []*yaml.Node) {
+	for i, key := range keys {
+		if !reservedNames[key.Value] {
+			continue
+		}
+
+		var message string
+		if !isSetting(key.Value, values[i]) {
+			message = fmt.Sprintf("task has the name of a setting, rename the task. task: %s", key.Value)
+		} else if key.Value == "dotenv" || key.Value == "include" {
+			paths, _ := parseStringList(values[i])
+			for _, value := range paths {
+				if commandLikePattern.MatchString(value) {
+					message = fmt.Sprintf("%s is a setting but looks like a command, rename the task. %s: %s", key.Value, key.Value, value)
+					break
+				}
+			}
+		}
+		if message == "" {
+			continue
+		}
+		l.issues = append(l.issues, &LintIssue{
+			Path:     path,
+			Line:     key.Line,
+			Column:   key.Column,
+			Severity: LintWarning,
+			Rule:     "reserved-name",
+			Task:     key.Value,
+			Message:  message,
+		})
+	}
+}
+
+func collectAliases(node *yaml.Node, aliases map[string]bool) {
+	if node.Kind == yaml.AliasNode {
+		aliases[node.Value] = true
+	}
+	for _, child := range node.Content {
+		collectAliases(child, aliases)
+	}
+}
+
+// ShowLintIssues shows the issues in text or JSON, and returns whether any error is found.
+func ShowLintIssues(issues []*LintIssue, usesJSON bool) bool {
+	hasError := false
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			hasError = true
+		}
+	}
+
+	if usesJSON {
+		if issues == nil {
+			issues = []*LintIssue{}
+		}
+		buf, _ := json.MarshalIndent(issues, "", "  ")
+		Printf("%s", buf)
+		return hasError
+	}
+
+	for _, issue := range issues {
+		Printf("%s", issue.String())
+	}
+	return hasError
+}
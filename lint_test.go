@@ -0,0 +1,229 @@
+package main
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func findLintIssues(issues []*LintIssue, rule string) []*LintIssue {
+	var found []*LintIssue
+	for _, issue := range issues {
+		if issue.Rule == rule {
+			found = append(found, issue)
+		}
+	}
+	return found
+}
+
+func TestLintConfig(t *testing.T) {
+	t.Run("When the config is invalid.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		issues := LintConfig("taskal.yml", "build:\n  cmd: make\n")
+
+		expected := []*LintIssue{
+			{Path: "taskal.yml", Line: 2, Column: 3, Severity: LintError, Rule: "config", Message: "unknown key in task definition: cmd. task: build"},
+		}
+		assert.Equal(expected, issues)
+	})
+
+	t.Run("When a task is defined twice by an included file.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "test: echo test\ninclude: fixtures/include/shared/go.yml\n"
+		issues := LintConfig("taskal.yml", buf)
+
+		assert.Len(issues, 1)
+		expected := "shadowed-name"
+		assert.Equal(expected, issues[0].Rule)
+		expected2 := "fixtures/include/shared/go.yml"
+		assert.Equal(expected2, issues[0].Path)
+		expected3 := LintError
+		assert.Equal(expected3, issues[0].Severity)
+	})
+
+	t.Run("When several tasks collide with included tasks.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "fmt: echo fmt\nb: echo b\nempty: []\ninclude:\n  - fixtures/include/shared/fmt.yml\n  - fixtures/include/shared/alias.yml\n"
+		issues := LintConfig("taskal.yml", buf)
+
+		expected := []*LintIssue{
+			{Path: "fixtures/include/shared/alias.yml", Line: 1, Severity: LintError, Rule: "shadowed-name", Task: "build", Message: "task name or alias is already used. task: build, name: b, other: b"},
+			{Path: "fixtures/include/shared/fmt.yml", Line: 1, Severity: LintError, Rule: "shadowed-name", Task: "fmt", Message: "task is already defined. task: fmt"},
+		}
+		assert.Equal(expected, findLintIssues(issues, "shadowed-name"))
+
+		assert.Len(findLintIssues(issues, "empty-task"), 1)
+	})
+
+	t.Run("When the config has no problems.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "_base: &base\n  desc: base\nbuild:\n  <<: *base\n  cmds: [make]\ntest:\n  deps: [build]\n  cmds: [make test]\n"
+		issues := LintConfig("taskal.yml", buf)
+
+		assert.Empty(issues)
+	})
+
+	t.Run("When a task is empty.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		issues := findLintIssues(LintConfig("taskal.yml", "build: []\ntest:\n  desc: test\n"), "empty-task")
+
+		assert.Len(issues, 2)
+		expected := &LintIssue{Path: "taskal.yml", Line: 1, Severity: LintWarning, Rule: "empty-task", Task: "build", Message: "task has no commands and no dependencies. task: build"}
+		assert.Equal(expected, issues[0])
+		expected2 := 2
+		assert.Equal(expected2, issues[1].Line)
+	})
+
+	t.Run("When a hidden entry is never referenced.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "_unused: &unused\n  desc: unused\n_plain: echo\n_used: &used\n  desc: used\n_dep: echo dep\nbuild:\n  <<: *used\n  deps: [_dep]\n  cmds: [make]\n"
+		issues := findLintIssues(LintConfig("taskal.yml", buf), "unused-hidden")
+
+		assert.Len(issues, 2)
+		expected := "_unused"
+		assert.Equal(expected, issues[0].Task)
+		expected2 := "_plain"
+		assert.Equal(expected2, issues[1].Task)
+		expected3 := 3
+		assert.Equal(expected3, issues[1].Line)
+	})
+
+	t.Run("When tasks have the names of settings.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "env: printenv | sort\nvars:\n  FOO: foo\ndotenv: cat .env\n"
+		issues := findLintIssues(LintConfig("taskal.yml", buf), "reserved-name")
+
+		expected := []*LintIssue{
+			{Path: "taskal.yml", Line: 1, Column: 1, Severity: LintWarning, Rule: "reserved-name", Task: "env", Message: "task has the name of a setting, rename the task. task: env"},
+			{Path: "taskal.yml", Line: 4, Column: 1, Severity: LintWarning, Rule: "reserved-name", Task: "dotenv", Message: "dotenv is a setting but looks like a command, rename the task. dotenv: cat .env"},
+		}
+		assert.Equal(expected, issues)
+	})
+
+	t.Run("When commands are duplicated.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  - make\n  - make\ncompile:\n  - make\n  - make\n"
+		issues := findLintIssues(LintConfig("taskal.yml", buf), "duplicate-command")
+
+		assert.Len(issues, 3)
+		expected := "command is repeated in the task. task: build, command: make"
+		assert.Equal(expected, issues[0].Message)
+		expected2 := "task has the same commands as another task. task: compile, other: build"
+		assert.Equal(expected2, issues[2].Message)
+	})
+
+	t.Run("When a dependency is not defined.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		issues := findLintIssues(LintConfig("taskal.yml", "test:\n  deps: [build]\n  cmds: [make test]\n"), "undefined-dependency")
+
+		expected := []*LintIssue{
+			{Path: "taskal.yml", Line: 1, Severity: LintError, Rule: "undefined-dependency", Task: "test", Message: "dependent task is not defined. task: test, dependency: build"},
+		}
+		assert.Equal(expected, issues)
+	})
+
+	t.Run("When a dependency is given by an alias.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build:\n  aliases: compile\n  cmds: go build \"$@\"\ntest:\n  deps: [compile]\n  cmds: [go test]\n"
+		issues := LintConfig("taskal.yml", buf)
+
+		assert.Len(issues, 1)
+		expected := "unused-args"
+		assert.Equal(expected, issues[0].Rule)
+	})
+
+	t.Run("When a task shadows a builtin command.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		issues := findLintIssues(LintConfig("taskal.yml", "help: echo help\n"), "shadowed-name")
+
+		assert.Len(issues, 1)
+		expected := "task shadows the builtin command. task: help"
+		assert.Equal(expected, issues[0].Message)
+	})
+
+	t.Run("When a dependency uses arguments.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build: go build \"$@\"\ntest:\n  deps: [build]\n  cmds: [go test $*]\n"
+		issues := findLintIssues(LintConfig("taskal.yml", buf), "unused-args")
+
+		assert.Len(issues, 1)
+		expected := "task uses arguments but runs without them as a dependency. task: build, dependency of: test"
+		assert.Equal(expected, issues[0].Message)
+	})
+
+	t.Run("When commands use unportable shell constructs.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "check:\n  - '[[ -f go.mod ]] && echo ok'\n  - source .env\n  - make &> /dev/null\n  - diff <(ls a) <(ls b)\n  - echo -e 'a\\tb'\n  - '[ -f go.mod ] && . ./.env'\n"
+		issues := findLintIssues(LintConfig("taskal.yml", buf), "unportable-shell")
+
+		assert.Len(issues, 5)
+		expected := "[[ is not supported by sh, use [ instead. task: check, command: [[ -f go.mod ]] && echo ok"
+		assert.Equal(expected, issues[0].Message)
+	})
+}
+
+func TestLintIssue_String(t *testing.T) {
+	assert := assert2.New(t)
+
+	issue := &LintIssue{Path: "taskal.yml", Line: 3, Severity: LintWarning, Rule: "empty-task", Message: "task has no commands"}
+
+	expected := "taskal.yml:3: warning: task has no commands (empty-task)"
+	assert.Equal(expected, issue.String())
+}
+
+func TestShowLintIssues(t *testing.T) {
+	issues := []*LintIssue{
+		{Path: "taskal.yml", Line: 1, Severity: LintWarning, Rule: "empty-task", Task: "build", Message: "empty"},
+		{Path: "taskal.yml", Line: 2, Severity: LintError, Rule: "undefined-dependency", Task: "test", Message: "undefined"},
+	}
+
+	t.Run("When the output is text.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		actual := ShowLintIssues(issues, false)
+
+		assert.True(actual)
+		expected := "taskal.yml:1: warning: empty (empty-task)\ntaskal.yml:2: error: undefined (undefined-dependency)\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the output is JSON.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		actual := ShowLintIssues(issues[:1], true)
+
+		assert.False(actual)
+		expected := "[\n  {\n    \"path\": \"taskal.yml\",\n    \"line\": 1,\n    \"severity\": \"warning\",\n    \"rule\": \"empty-task\",\n    \"task\": \"build\",\n    \"message\": \"empty\"\n  }\n]\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When no issues are found in JSON.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		ShowLintIssues(nil, true)
+
+		expected := "[]\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/fatih/color"
 	"io"
 	"os"
+	"strings"
+	"sync"
 )
 
 var (
@@ -12,39 +14,107 @@ var (
 	Stderr io.Writer = os.Stderr
 )
 
+type LogLevel int
+
+const (
+	LogLevelError LogLevel = iota
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+	LogLevelTrace
+)
+
+var logLevelNames = map[string]LogLevel{
+	"error": LogLevelError,
+	"warn":  LogLevelWarn,
+	"info":  LogLevelInfo,
+	"debug": LogLevelDebug,
+	"trace": LogLevelTrace,
+}
+
+var logLevel = LogLevelInfo
+
+var logMutex sync.Mutex
+
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+}
+
+func CurrentLogLevel() LogLevel {
+	return logLevel
+}
+
+func ParseLogLevel(str string) (LogLevel, error) {
+	if level, ok := logLevelNames[strings.ToLower(strings.TrimSpace(str))]; ok {
+		return level, nil
+	}
+	return LogLevelInfo, fmt.Errorf("unknown log level: %s", str)
+}
+
 func Printf(format string, a ...interface{}) {
 	fmt.Fprintf(Stdout, format, a...)
 	fmt.Fprintln(Stdout)
 }
 
+func Trace(format string, a ...interface{}) {
+	if logLevel >= LogLevelTrace {
+		writeLog(Stdout, color.HiBlackString("[TRACE]"), format, a...)
+	}
+}
+
 func Debug(format string, a ...interface{}) {
-	if DEBUG {
-		fmt.Fprintf(Stdout, color.HiBlackString("[DEBUG]"))
-		fmt.Fprintf(Stdout, color.HiWhiteString("[%s] ", TimeStamp()))
-		fmt.Fprintf(Stdout, format, a...)
-		fmt.Fprintln(Stdout)
+	if logLevel >= LogLevelDebug {
+		writeLog(Stdout, color.HiBlackString("[DEBUG]"), format, a...)
 	}
 }
 
 func Info(format string, a ...interface{}) {
-	fmt.Fprintf(Stdout, color.HiCyanString("[INFO]"))
-	fmt.Fprintf(Stdout, color.HiWhiteString("[%s] ", TimeStamp()))
-	fmt.Fprintf(Stdout, format, a...)
-	fmt.Fprintln(Stdout)
+	if logLevel >= LogLevelInfo {
+		writeLog(Stdout, color.HiCyanString("[INFO]"), format, a...)
+	}
 }
 
 func Warn(format string, a ...interface{}) {
-	fmt.Fprintf(Stderr, color.HiYellowString("[WARN]"))
-	fmt.Fprintf(Stderr, color.HiWhiteString("[%s] ", TimeStamp()))
-	fmt.Fprintf(Stderr, format, a...)
-	fmt.Fprintln(Stderr)
+	if logLevel >= LogLevelWarn {
+		writeLog(Stderr, color.HiYellowString("[WARN]"), format, a...)
+	}
 }
 
 func Error(format string, a ...interface{}) {
-	fmt.Fprintf(Stderr, color.HiRedString("[ERROR]"))
-	fmt.Fprintf(Stderr, color.HiWhiteString("[%s] ", TimeStamp()))
-	fmt.Fprintf(Stderr, format, a...)
-	fmt.Fprintln(Stderr)
+	writeLog(Stderr, color.HiRedString("[ERROR]"), format, a...)
+}
+
+// InfoTo writes the log of a task to its output, so the log is prefixed or grouped with the output of the commands.
+func InfoTo(output Output, format string, a ...interface{}) {
+	// The commands of sh vars are logged by shVarCache.Evaluate with their own label.
+	if _, ok := output.(*captureOutput); ok {
+		return
+	}
+	if logLevel >= LogLevelInfo {
+		writeOutputLog(logOutput(output).Stdout(), color.HiCyanString("[INFO]"), format, a...)
+	}
+}
+
+// ErrorTo writes the error of a task to its output like InfoTo.
+func ErrorTo(output Output, format string, a ...interface{}) {
+	writeOutputLog(logOutput(output).Stderr(), color.HiRedString("[ERROR]"), format, a...)
+}
+
+// writeLog writes a whole log line at once so that lines from concurrent tasks are not mixed.
+func writeLog(w io.Writer, label string, format string, a ...interface{}) {
+	line := label + color.HiWhiteString("[%s] ", TimeStamp()) + fmt.Sprintf(format, a...) + "\n"
+	writeLocked(w, line)
+}
+
+func writeOutputLog(w io.Writer, label string, format string, a ...interface{}) {
+	line := label + color.HiWhiteString("[%s] ", TimeStamp()) + fmt.Sprintf(format, a...) + "\n"
+	writeOutput(w, line)
+}
+
+func writeLocked(w io.Writer, str string) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	io.WriteString(w, str)
 }
 
 func TimeStamp() string {
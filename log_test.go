@@ -206,3 +206,161 @@ func TestError(t *testing.T) {
 		assert.Equal(expected2, errbuf.String())
 	})
 }
+
+func TestInfoTo(t *testing.T) {
+	defer HookStdio()
+
+	outbuf := &bytes.Buffer{}
+	errbuf := &bytes.Buffer{}
+	Stdout = outbuf
+	Stderr = errbuf
+	color.NoColor = true
+
+	t.Run("When the output is prefixed.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		outbuf.Reset()
+		errbuf.Reset()
+
+		output := NewOutput(OutputPrefixed, "foo", 3)
+		InfoTo(output, "Test Info")
+		ErrorTo(output, "Test Error")
+
+		expected := "foo | [INFO][15:04:05] Test Info\n"
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := "foo | [ERROR][15:04:05] Test Error\n"
+		assert.Equal(expected2, errbuf.String())
+	})
+
+	t.Run("When the output is grouped.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		outbuf.Reset()
+		errbuf.Reset()
+
+		output := NewOutput(OutputGrouped, "foo", 3)
+		InfoTo(output, "Test Info")
+		output.Stdout().Write([]byte("bar\n"))
+
+		expected := ""
+		assert.Equal(expected, outbuf.String())
+
+		output.Close()
+
+		expected2 := "[INFO][15:04:05] Test Info\nbar\n"
+		assert.Equal(expected2, outbuf.String())
+	})
+
+	t.Run("When the output is captured.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		outbuf.Reset()
+		errbuf.Reset()
+
+		output := &captureOutput{output: &interleavedOutput{}}
+		InfoTo(output, "Test Info")
+
+		// The commands of sh vars are logged by shVarCache.Evaluate.
+		expected := ""
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := ""
+		assert.Equal(expected2, output.stdout.String())
+	})
+}
+
+func TestParseLogLevel(t *testing.T) {
+	t.Run("When passing known level.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := ParseLogLevel(" DEBUG ")
+
+		assert.NoError(err)
+
+		expected := LogLevelDebug
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When passing unknown level.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := ParseLogLevel("loud")
+
+		expected := "unknown log level: loud"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestLogLevel(t *testing.T) {
+	defer HookStdio()
+	defer SetLogLevel(CurrentLogLevel())
+
+	outbuf := &bytes.Buffer{}
+	errbuf := &bytes.Buffer{}
+	Stdout = outbuf
+	Stderr = errbuf
+
+	logAll := func() {
+		outbuf.Reset()
+		errbuf.Reset()
+
+		Trace("trace")
+		Debug("debug")
+		Info("info")
+		Warn("warn")
+		Error("error")
+	}
+
+	t.Run("When log level is trace.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		SetLogLevel(LogLevelTrace)
+		logAll()
+
+		expected := "[TRACE][15:04:05] trace\n[DEBUG][15:04:05] debug\n[INFO][15:04:05] info\n"
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := "[WARN][15:04:05] warn\n[ERROR][15:04:05] error\n"
+		assert.Equal(expected2, errbuf.String())
+	})
+
+	t.Run("When log level is info.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		SetLogLevel(LogLevelInfo)
+		logAll()
+
+		expected := "[INFO][15:04:05] info\n"
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := "[WARN][15:04:05] warn\n[ERROR][15:04:05] error\n"
+		assert.Equal(expected2, errbuf.String())
+	})
+
+	t.Run("When log level is warn.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		SetLogLevel(LogLevelWarn)
+		logAll()
+
+		expected := ""
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := "[WARN][15:04:05] warn\n[ERROR][15:04:05] error\n"
+		assert.Equal(expected2, errbuf.String())
+	})
+
+	t.Run("When log level is error.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		SetLogLevel(LogLevelError)
+		logAll()
+
+		expected := ""
+		assert.Equal(expected, outbuf.String())
+
+		expected2 := "[ERROR][15:04:05] error\n"
+		assert.Equal(expected2, errbuf.String())
+	})
+}
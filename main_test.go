@@ -30,6 +30,8 @@ func HookStdio() {
 func setup() {
 	HookStdio()
 
+	SetLogLevel(LogLevelDebug)
+
 	Now = func() time.Time {
 		return time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local)
 	}
@@ -41,6 +43,8 @@ func tearDown() {
 	Stderr = os.Stderr
 	color.NoColor = false
 
+	SetLogLevel(LogLevelInfo)
+
 	Now = func() time.Time {
 		return time.Now()
 	}
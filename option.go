@@ -3,29 +3,75 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type Option interface {
 	WillBeShowTasks() bool
+	ShowsHiddenTasks() bool
+	WillLint() bool
+	UsesJSON() bool
 	BeDryRun() bool
 	HasSpecifiedTasks() bool
 	SpecifiedTasks() []string
+	MatchesPrefix() bool
+	Tags() []string
+	SkippedTags() []string
 	ConfigPath() string
+	WorkingDir() string
 	TaskArgs() []string
+	TaskArgsOf(string) []string
+	Variables() []string
+	LogLevel() LogLevel
+	UsesFixedExitCode() bool
+	Jobs() int
+	KeepsGoing() bool
+	OutputMode() string
+	Timeout() time.Duration
 }
 
 type OptionImpl struct {
 	willBeShowTasks bool
+	showsHidden     bool
+	willLint        bool
+	usesJSON        bool
 	beDryRun        bool
 	specifiedTasks  []string
+	matchesPrefix   bool
+	tags            []string
+	skippedTags     []string
 	configPath      string
+	workingDir      string
 	taskArgs        []string
+	variables       []string
+	scopedTaskArgs  map[string][]string
+	logLevel        LogLevel
+	fixedExitCode   bool
+	jobs            int
+	keepGoing       bool
+	outputMode      string
+	timeout         time.Duration
 }
 
 func (o *OptionImpl) WillBeShowTasks() bool {
 	return o.willBeShowTasks
 }
 
+func (o *OptionImpl) ShowsHiddenTasks() bool {
+	return o.showsHidden
+}
+
+func (o *OptionImpl) WillLint() bool {
+	return o.willLint
+}
+
+func (o *OptionImpl) UsesJSON() bool {
+	return o.usesJSON
+}
+
 func (o *OptionImpl) BeDryRun() bool {
 	return o.beDryRun
 }
@@ -38,14 +84,71 @@ func (o *OptionImpl) SpecifiedTasks() []string {
 	return o.specifiedTasks
 }
 
+// MatchesPrefix reports whether a task can be specified by an unambiguous prefix of its name.
+func (o *OptionImpl) MatchesPrefix() bool {
+	return o.matchesPrefix
+}
+
+// Tags returns the tags of the tasks to be run.
+func (o *OptionImpl) Tags() []string {
+	return o.tags
+}
+
+// SkippedTags returns the tags of the tasks not to be run.
+func (o *OptionImpl) SkippedTags() []string {
+	return o.skippedTags
+}
+
 func (o *OptionImpl) ConfigPath() string {
 	return o.configPath
 }
 
+func (o *OptionImpl) WorkingDir() string {
+	return o.workingDir
+}
+
 func (o *OptionImpl) TaskArgs() []string {
 	return o.taskArgs
 }
 
+// TaskArgsOf returns the arguments given to the task as TASK[ARG,...],
+// or the arguments after -- if they are not given.
+func (o *OptionImpl) TaskArgsOf(name string) []string {
+	if args, ok := o.scopedTaskArgs[name]; ok {
+		return args
+	}
+	return o.taskArgs
+}
+
+// Variables returns the variables given as KEY=value arguments.
+func (o *OptionImpl) Variables() []string {
+	return o.variables
+}
+
+func (o *OptionImpl) LogLevel() LogLevel {
+	return o.logLevel
+}
+
+func (o *OptionImpl) UsesFixedExitCode() bool {
+	return o.fixedExitCode
+}
+
+func (o *OptionImpl) Jobs() int {
+	return o.jobs
+}
+
+func (o *OptionImpl) KeepsGoing() bool {
+	return o.keepGoing
+}
+
+func (o *OptionImpl) OutputMode() string {
+	return o.outputMode
+}
+
+func (o *OptionImpl) Timeout() time.Duration {
+	return o.timeout
+}
+
 var ParseOption = func(args []string) (Option, error) {
 	option := &OptionImpl{}
 
@@ -56,21 +159,133 @@ var ParseOption = func(args []string) (Option, error) {
 		fmt.Fprintln(f.Output(), "Usage: taskal [options...] [tasks ...] -- [optional args ...]")
 		f.PrintDefaults()
 	}
-	f.BoolVar(&option.willBeShowTasks, "T", false, "Show all tasks.")
+	f.BoolVar(&option.willBeShowTasks, "T", false, "Show all tasks, or the tasks matching the patterns given as arguments.")
+	f.BoolVar(&option.showsHidden, "all", false, "Show hidden tasks too. (with -T)")
+	f.BoolVar(&option.willLint, "lint", false, "Check the config file for problems without running any task.")
+	f.BoolVar(&option.usesJSON, "json", false, "Output in JSON. (with -T or -lint)")
 	f.BoolVar(&option.beDryRun, "n", false, "Do a dry run without executing actions.")
-	f.StringVar(&option.configPath, "c", "taskal.yml", "taskal -c [CONFIGFILE]")
+	f.BoolVar(&option.matchesPrefix, "prefix", false, "Run the task whose name starts with the given name, if it is unambiguous.")
+	f.Var((*stringsFlag)(&option.tags), "tag", "Run the tasks with the tag. Can be given multiple times or separated by commas.")
+	f.Var((*stringsFlag)(&option.skippedTags), "skip-tag", "Do not run the tasks with the tag. Can be given multiple times or separated by commas.")
+	f.StringVar(&option.configPath, "c", "", "taskal -c [CONFIGFILE] (default: taskal.yml, taskal.yaml or .taskal.yml in the current or a parent directory)")
+	f.StringVar(&option.workingDir, "C", "", "Change to DIR before doing anything.")
+	verbose := f.Bool("v", false, "Show debug logs.")
+	veryVerbose := f.Bool("vv", false, "Show debug and trace logs.")
+	quiet := f.Bool("q", false, "Show only warnings and errors.")
+	f.IntVar(&option.jobs, "j", 1, "Run N tasks in parallel.")
+	f.BoolVar(&option.keepGoing, "k", false, "Keep going with other tasks when some tasks fail.")
+	f.StringVar(&option.outputMode, "o", OutputInterleaved, fmt.Sprintf("Output mode of commands. (%s)", strings.Join(OutputModes, ", ")))
+	f.DurationVar(&option.timeout, "timeout", 0, "Terminate commands when all tasks are not completed within the duration. (e.g. 30s, 5m)")
+	f.BoolVar(&option.fixedExitCode, "fixed-exit-code", false, "Exit with 4 on any command failure instead of the exit status of the command.")
+
+	positionalArgs, err := parseFlags(f, args[1:])
+	if err != nil {
+		return nil, err
+	}
 
-	if err := f.Parse(args[1:]); err != nil {
+	logLevel, err := parseLogLevel(*verbose, *veryVerbose, *quiet)
+	if err != nil {
+		fmt.Fprintln(f.Output(), err.Error())
 		return nil, err
 	}
+	option.logLevel = logLevel
 
-	option.specifiedTasks, option.taskArgs = parseTaskAndArgs(f.Args())
+	if option.jobs < 1 {
+		err := fmt.Errorf("-j must be 1 or more")
+		fmt.Fprintln(f.Output(), err.Error())
+		return nil, err
+	}
 
-	Debug("%v, %v", option.specifiedTasks, option.taskArgs)
+	if !IsValidOutputMode(option.outputMode) {
+		err := fmt.Errorf("unknown output mode: %s", option.outputMode)
+		fmt.Fprintln(f.Output(), err.Error())
+		return nil, err
+	}
+
+	option.specifiedTasks, option.taskArgs = parseTaskAndArgs(positionalArgs)
+	// With -T, the arguments are the patterns of the tasks to show, which may contain `=` and `[...]`.
+	if !option.willBeShowTasks {
+		option.specifiedTasks, option.variables = parseVariables(option.specifiedTasks)
+		option.specifiedTasks, option.scopedTaskArgs, err = parseScopedTaskArgs(option.specifiedTasks)
+		if err != nil {
+			fmt.Fprintln(f.Output(), err.Error())
+			return nil, err
+		}
+	}
 
 	return option, nil
 }
 
+// parseFlags parses the flags given before and after the task names until `--`,
+// and returns the other arguments in order.
+func parseFlags(f *flag.FlagSet, args []string) ([]string, error) {
+	end := len(args)
+	for i, arg := range args {
+		if arg == "--" {
+			end = i
+			break
+		}
+	}
+
+	var positionalArgs []string
+	rest := args[:end]
+	for {
+		if err := f.Parse(rest); err != nil {
+			return nil, err
+		}
+		rest = f.Args()
+		for len(rest) > 0 && !isFlagArg(rest[0]) {
+			positionalArgs = append(positionalArgs, rest[0])
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return append(positionalArgs, args[end:]...), nil
+		}
+	}
+}
+
+func isFlagArg(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-'
+}
+
+// stringsFlag is a flag which can be given multiple times, or as comma separated values.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+var parseLogLevel = func(verbose bool, veryVerbose bool, quiet bool) (LogLevel, error) {
+	if quiet && (verbose || veryVerbose) {
+		return LogLevelInfo, fmt.Errorf("-q cannot be used with -v or -vv")
+	}
+
+	if quiet {
+		return LogLevelWarn, nil
+	} else if veryVerbose {
+		return LogLevelTrace, nil
+	} else if verbose {
+		return LogLevelDebug, nil
+	}
+
+	if env := os.Getenv("TASKAL_LOG_LEVEL"); env != "" {
+		if logLevel, err := ParseLogLevel(env); err == nil {
+			return logLevel, nil
+		}
+		Warn("Unknown log level in TASKAL_LOG_LEVEL. level: %s", env)
+	}
+	return LogLevelInfo, nil
+}
+
 var parseTaskAndArgs = func(args []string) ([]string, []string) {
 	if len(args) == 0 {
 		return []string{}, []string{}
@@ -84,3 +299,67 @@ var parseTaskAndArgs = func(args []string) ([]string, []string) {
 
 	return args, []string{}
 }
+
+var variablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// parseVariables separates KEY=value arguments from the task names.
+func parseVariables(args []string) ([]string, []string) {
+	tasks := []string{}
+	var variables []string
+	for _, arg := range args {
+		if variablePattern.MatchString(arg) {
+			variables = append(variables, arg)
+		} else {
+			tasks = append(tasks, arg)
+		}
+	}
+	return tasks, variables
+}
+
+var scopedTaskArgsPattern = regexp.MustCompile(`^([^\[\]]+)\[(.*)\]$`)
+
+// parseScopedTaskArgs separates the arguments given as TASK[ARG,...] from the task names.
+// A comma in an argument is escaped with a backslash.
+// Since `[...]` always gives the arguments, task patterns cannot have character classes.
+func parseScopedTaskArgs(tasks []string) ([]string, map[string][]string, error) {
+	names := []string{}
+	scopedTaskArgs := map[string][]string{}
+	for _, task := range tasks {
+		if !strings.ContainsAny(task, "[]") {
+			names = append(names, task)
+			continue
+		}
+
+		match := scopedTaskArgsPattern.FindStringSubmatch(task)
+		if match == nil && strings.ContainsAny(task, "*?") {
+			return nil, nil, fmt.Errorf("character classes are not supported in task patterns: %s", task)
+		}
+		if match == nil {
+			return nil, nil, fmt.Errorf("invalid task arguments: %s", task)
+		}
+		names = append(names, match[1])
+		scopedTaskArgs[match[1]] = splitTaskArgs(match[2])
+	}
+	return names, scopedTaskArgs, nil
+}
+
+func splitTaskArgs(str string) []string {
+	args := []string{}
+	if str == "" {
+		return args
+	}
+
+	var arg strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) && str[i+1] == ',' {
+			arg.WriteByte(',')
+			i++
+		} else if str[i] == ',' {
+			args = append(args, arg.String())
+			arg.Reset()
+		} else {
+			arg.WriteByte(str[i])
+		}
+	}
+	return append(args, arg.String())
+}
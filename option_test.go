@@ -3,7 +3,9 @@ package main
 import (
 	assert2 "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"os"
 	"testing"
+	"time"
 )
 
 type MockOption struct {
@@ -14,6 +16,18 @@ func (m *MockOption) WillBeShowTasks() bool {
 	return m.Called().Bool(0)
 }
 
+func (m *MockOption) ShowsHiddenTasks() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockOption) WillLint() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockOption) UsesJSON() bool {
+	return m.Called().Bool(0)
+}
+
 func (m *MockOption) BeDryRun() bool {
 	return m.Called().Bool(0)
 }
@@ -33,10 +47,36 @@ func (m *MockOption) SpecifiedTasks() []string {
 	return ret
 }
 
+func (m *MockOption) MatchesPrefix() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockOption) Tags() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		ret = append(ret, arg.(string))
+	}
+	return ret
+}
+
+func (m *MockOption) SkippedTags() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		ret = append(ret, arg.(string))
+	}
+	return ret
+}
+
 func (m *MockOption) ConfigPath() string {
 	return m.Called().String(0)
 }
 
+func (m *MockOption) WorkingDir() string {
+	return m.Called().String(0)
+}
+
 func (m *MockOption) TaskArgs() []string {
 	var ret []string
 	args := m.Called()
@@ -48,6 +88,52 @@ func (m *MockOption) TaskArgs() []string {
 	return ret
 }
 
+func (m *MockOption) TaskArgsOf(name string) []string {
+	var ret []string
+	args := m.Called(name)
+	for _, arg := range args {
+		if v, ok := arg.(string); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockOption) Variables() []string {
+	var ret []string
+	args := m.Called()
+	for _, arg := range args {
+		if v, ok := arg.(string); ok {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func (m *MockOption) LogLevel() LogLevel {
+	return m.Called().Get(0).(LogLevel)
+}
+
+func (m *MockOption) UsesFixedExitCode() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockOption) Jobs() int {
+	return m.Called().Int(0)
+}
+
+func (m *MockOption) KeepsGoing() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *MockOption) OutputMode() string {
+	return m.Called().String(0)
+}
+
+func (m *MockOption) Timeout() time.Duration {
+	return m.Called().Get(0).(time.Duration)
+}
+
 func TestOptionImpl_WillBeShowTasks(t *testing.T) {
 	t.Run("When show tasks flag filed was true.", func(t *testing.T) {
 		assert := assert2.New(t)
@@ -183,6 +269,19 @@ func TestOptionImpl_SpecifiedTasks(t *testing.T) {
 	})
 }
 
+func TestOptionImpl_LogLevel(t *testing.T) {
+	assert := assert2.New(t)
+
+	option := OptionImpl{
+		logLevel: LogLevelTrace,
+	}
+
+	actual := option.LogLevel()
+
+	expected := LogLevelTrace
+	assert.Equal(expected, actual)
+}
+
 func TestParseOption(t *testing.T) {
 	t.Run("When passing invalid flag.", func(t *testing.T) {
 		iobuffer.Reset()
@@ -244,7 +343,7 @@ func TestParseOption(t *testing.T) {
 		assert.True(option.WillBeShowTasks())
 		assert.False(option.BeDryRun())
 
-		expected2 := "taskal.yml"
+		expected2 := ""
 		assert.Equal(expected2, option.ConfigPath())
 	})
 
@@ -268,7 +367,7 @@ func TestParseOption(t *testing.T) {
 		assert.False(option.WillBeShowTasks())
 		assert.True(option.BeDryRun())
 
-		expected2 := "taskal.yml"
+		expected2 := ""
 		assert.Equal(expected2, option.ConfigPath())
 	})
 
@@ -297,6 +396,133 @@ func TestParseOption(t *testing.T) {
 		assert.Equal(expected2, option.ConfigPath())
 	})
 
+	t.Run("When passing variables.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		args := []string{
+			"taskal",
+			"deploy",
+			"ENV=staging",
+			"REGION=",
+			"notify",
+			"--",
+			"FOO=foo",
+		}
+
+		option, err := ParseOption(args)
+
+		assert.NoError(err)
+
+		expected := []string{"deploy", "notify"}
+		assert.Equal(expected, option.SpecifiedTasks())
+
+		expected2 := []string{"ENV=staging", "REGION="}
+		assert.Equal(expected2, option.Variables())
+
+		expected3 := []string{"FOO=foo"}
+		assert.Equal(expected3, option.TaskArgs())
+	})
+
+	t.Run("When passing task arguments in brackets.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		args := []string{
+			"taskal",
+			"build",
+			"test[-race,-run=Foo\\,Bar]",
+			"lint[]",
+			"--",
+			"-v",
+		}
+
+		option, err := ParseOption(args)
+
+		assert.NoError(err)
+
+		expected := []string{"build", "test", "lint"}
+		assert.Equal(expected, option.SpecifiedTasks())
+
+		expected2 := []string{"-v"}
+		assert.Equal(expected2, option.TaskArgsOf("build"))
+
+		expected3 := []string{"-race", "-run=Foo,Bar"}
+		assert.Equal(expected3, option.TaskArgsOf("test"))
+
+		expected4 := []string{}
+		assert.Equal(expected4, option.TaskArgsOf("lint"))
+	})
+
+	t.Run("When passing invalid task arguments.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		args := []string{
+			"taskal",
+			"test[-race",
+		}
+
+		option, err := ParseOption(args)
+
+		assert.Nil(option)
+
+		expected := "invalid task arguments: test[-race"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When passing a task pattern with a character class.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "test:[a-z]*"})
+
+		assert.Nil(option)
+
+		expected := "character classes are not supported in task patterns: test:[a-z]*"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When passing only variables.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		args := []string{
+			"taskal",
+			"ENV=staging",
+		}
+
+		option, err := ParseOption(args)
+
+		assert.NoError(err)
+
+		assert.False(option.HasSpecifiedTasks())
+	})
+
+	t.Run("When passing working directory flag.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		args := []string{
+			"taskal",
+			"-C",
+			"./fixtures",
+		}
+
+		option, err := ParseOption(args)
+
+		assert.NoError(err)
+
+		expected := "./fixtures"
+		assert.Equal(expected, option.WorkingDir())
+	})
+
 	t.Run("When passing specified tasks.", func(t *testing.T) {
 		iobuffer.Reset()
 
@@ -367,4 +593,277 @@ func TestParseOption(t *testing.T) {
 		expected7 := "-buzz"
 		assert.Equal(expected7, option.TaskArgs()[1])
 	})
+
+	t.Run("When passing log level flags.", func(t *testing.T) {
+		cases := []struct {
+			flag     string
+			expected LogLevel
+		}{
+			{"-q", LogLevelWarn},
+			{"-v", LogLevelDebug},
+			{"-vv", LogLevelTrace},
+		}
+
+		for _, c := range cases {
+			assert := assert2.New(t)
+
+			option, err := ParseOption([]string{"taskal", c.flag})
+
+			assert.NoError(err)
+
+			assert.Equal(c.expected, option.LogLevel())
+		}
+	})
+
+	t.Run("When passing fixed exit code flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-fixed-exit-code"})
+
+		assert.NoError(err)
+
+		assert.True(option.UsesFixedExitCode())
+	})
+
+	t.Run("When passing prefix flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-prefix", "bui"})
+
+		assert.NoError(err)
+
+		assert.True(option.MatchesPrefix())
+	})
+
+	t.Run("When passing tag flags.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-tag", "ci", "--tag", "lint, unit", "-skip-tag", "slow"})
+
+		assert.NoError(err)
+
+		expected := []string{"ci", "lint", "unit"}
+		assert.Equal(expected, option.Tags())
+
+		expected2 := []string{"slow"}
+		assert.Equal(expected2, option.SkippedTags())
+	})
+
+	t.Run("When passing all flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-T", "--all"})
+
+		assert.NoError(err)
+
+		assert.True(option.ShowsHiddenTasks())
+	})
+
+	t.Run("When passing show tasks flags with patterns.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-T", "^b.*[0-9]$", "build[0-9]", "a=b"})
+
+		assert.NoError(err)
+
+		expected := []string{"^b.*[0-9]$", "build[0-9]", "a=b"}
+		assert.Equal(expected, option.SpecifiedTasks())
+
+		assert.Empty(option.TaskArgsOf("build"))
+		assert.Empty(option.Variables())
+	})
+
+	t.Run("When passing lint and json flags.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "--lint", "-json"})
+
+		assert.NoError(err)
+
+		assert.True(option.WillLint())
+		assert.True(option.UsesJSON())
+	})
+
+	t.Run("When passing parallel flags.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-j", "3", "-k", "lint", "test"})
+
+		assert.NoError(err)
+
+		expected := 3
+		assert.Equal(expected, option.Jobs())
+
+		assert.True(option.KeepsGoing())
+	})
+
+	t.Run("When passing flags after task names.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "lint", "test", "-j", "3", "vet", "-k", "--", "-n"})
+
+		assert.NoError(err)
+
+		expected := []string{"lint", "test", "vet"}
+		assert.Equal(expected, option.SpecifiedTasks())
+
+		expected2 := 3
+		assert.Equal(expected2, option.Jobs())
+
+		assert.True(option.KeepsGoing())
+		assert.False(option.BeDryRun())
+
+		expected3 := []string{"-n"}
+		assert.Equal(expected3, option.TaskArgs())
+	})
+
+	t.Run("When passing no parallel flags.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "lint"})
+
+		assert.NoError(err)
+
+		expected := 1
+		assert.Equal(expected, option.Jobs())
+
+		assert.False(option.KeepsGoing())
+	})
+
+	t.Run("When passing invalid jobs.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-j", "0", "lint"})
+
+		assert.Nil(option)
+
+		assert.Error(err)
+
+		expected := "-j must be 1 or more\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When passing output mode flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-o", "grouped", "lint"})
+
+		assert.NoError(err)
+
+		expected := OutputGrouped
+		assert.Equal(expected, option.OutputMode())
+	})
+
+	t.Run("When passing no output mode flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "lint"})
+
+		assert.NoError(err)
+
+		expected := OutputInterleaved
+		assert.Equal(expected, option.OutputMode())
+	})
+
+	t.Run("When passing unknown output mode.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-o", "fancy", "lint"})
+
+		assert.Nil(option)
+
+		assert.Error(err)
+
+		expected := "unknown output mode: fancy\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When passing timeout flag.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-timeout", "1m30s", "lint"})
+
+		assert.NoError(err)
+
+		expected := 90 * time.Second
+		assert.Equal(expected, option.Timeout())
+	})
+
+	t.Run("When passing no log level flags.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal"})
+
+		assert.NoError(err)
+
+		expected := LogLevelInfo
+		assert.Equal(expected, option.LogLevel())
+	})
+
+	t.Run("When passing quiet and verbose flags.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option, err := ParseOption([]string{"taskal", "-q", "-v"})
+
+		assert.Nil(option)
+
+		assert.Error(err)
+
+		expected := "-q cannot be used with -v or -vv\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
+
+func Test_parseLogLevel(t *testing.T) {
+	defer os.Unsetenv("TASKAL_LOG_LEVEL")
+
+	t.Run("When TASKAL_LOG_LEVEL is set.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		os.Setenv("TASKAL_LOG_LEVEL", "error")
+
+		actual, err := parseLogLevel(false, false, false)
+
+		assert.NoError(err)
+
+		expected := LogLevelError
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When TASKAL_LOG_LEVEL is set and flag is passed.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		os.Setenv("TASKAL_LOG_LEVEL", "error")
+
+		actual, err := parseLogLevel(true, false, false)
+
+		assert.NoError(err)
+
+		expected := LogLevelDebug
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When TASKAL_LOG_LEVEL is unknown.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		os.Setenv("TASKAL_LOG_LEVEL", "loud")
+
+		actual, err := parseLogLevel(false, false, false)
+
+		assert.NoError(err)
+
+		expected := LogLevelInfo
+		assert.Equal(expected, actual)
+
+		expected2 := "[WARN][15:04:05] Unknown log level in TASKAL_LOG_LEVEL. level: loud\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
 }
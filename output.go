@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fatih/color"
+	"hash/fnv"
+	"io"
+	"sync"
+)
+
+const (
+	OutputInterleaved = "interleaved"
+	OutputPrefixed    = "prefixed"
+	OutputGrouped     = "grouped"
+)
+
+var OutputModes = []string{
+	OutputInterleaved,
+	OutputPrefixed,
+	OutputGrouped,
+}
+
+// Output holds the writers that the commands of a task write to.
+type Output interface {
+	Stdout() io.Writer
+	Stderr() io.Writer
+	Close()
+}
+
+var NewOutput = func(mode string, taskName string, width int) Output {
+	switch mode {
+	case OutputPrefixed:
+		prefix := prefixColor(taskName).Sprintf("%-*s |", width, taskName) + " "
+		return &prefixedOutput{
+			stdout: &prefixWriter{w: Stdout, prefix: prefix},
+			stderr: &prefixWriter{w: Stderr, prefix: prefix},
+		}
+	case OutputGrouped:
+		return &groupedOutput{}
+	default:
+		return &interleavedOutput{}
+	}
+}
+
+// logOutput returns the output which the logs of the commands written to the output go to.
+// The logs of the commands of sh vars go to the output of the task instead of the captured output.
+func logOutput(output Output) Output {
+	if capture, ok := output.(*captureOutput); ok {
+		return capture.output
+	}
+	return output
+}
+
+// writeOutput writes the string to the writer of an output at once.
+// The writers of prefixed and grouped outputs are synchronized by themselves.
+func writeOutput(w io.Writer, str string) {
+	switch w.(type) {
+	case *prefixWriter, *groupedWriter:
+		io.WriteString(w, str)
+	default:
+		writeLocked(w, str)
+	}
+}
+
+func IsValidOutputMode(mode string) bool {
+	for _, m := range OutputModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+type interleavedOutput struct {
+}
+
+func (o *interleavedOutput) Stdout() io.Writer {
+	return Stdout
+}
+
+func (o *interleavedOutput) Stderr() io.Writer {
+	return Stderr
+}
+
+func (o *interleavedOutput) Close() {
+}
+
+type prefixedOutput struct {
+	stdout *prefixWriter
+	stderr *prefixWriter
+}
+
+func (o *prefixedOutput) Stdout() io.Writer {
+	return o.stdout
+}
+
+func (o *prefixedOutput) Stderr() io.Writer {
+	return o.stderr
+}
+
+func (o *prefixedOutput) Close() {
+	o.stdout.Flush()
+	o.stderr.Flush()
+}
+
+var prefixColors = []color.Attribute{
+	color.FgHiCyan,
+	color.FgHiMagenta,
+	color.FgHiGreen,
+	color.FgHiYellow,
+	color.FgHiBlue,
+	color.FgCyan,
+	color.FgMagenta,
+	color.FgGreen,
+	color.FgYellow,
+	color.FgBlue,
+}
+
+func prefixColor(taskName string) *color.Color {
+	h := fnv.New32a()
+	h.Write([]byte(taskName))
+	return color.New(prefixColors[h.Sum32()%uint32(len(prefixColors))])
+}
+
+// prefixWriter writes each line with the prefix.
+// An incomplete line is kept until the rest of the line is written or Flush is called.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+	mutex  sync.Mutex
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		writeLocked(p.w, p.prefix+string(p.buf[:i+1]))
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+func (p *prefixWriter) Flush() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.buf) > 0 {
+		writeLocked(p.w, fmt.Sprintf("%s%s\n", p.prefix, p.buf))
+		p.buf = nil
+	}
+}
+
+type outputChunk struct {
+	w    io.Writer
+	data []byte
+}
+
+// groupedOutput keeps all the output of a task and writes it at once when the task is completed.
+type groupedOutput struct {
+	chunks []outputChunk
+	mutex  sync.Mutex
+}
+
+func (o *groupedOutput) Stdout() io.Writer {
+	return &groupedWriter{output: o, w: Stdout}
+}
+
+func (o *groupedOutput) Stderr() io.Writer {
+	return &groupedWriter{output: o, w: Stderr}
+}
+
+func (o *groupedOutput) Close() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
+	for _, chunk := range o.chunks {
+		chunk.w.Write(chunk.data)
+	}
+	o.chunks = nil
+}
+
+type groupedWriter struct {
+	output *groupedOutput
+	w      io.Writer
+}
+
+func (g *groupedWriter) Write(data []byte) (int, error) {
+	g.output.mutex.Lock()
+	defer g.output.mutex.Unlock()
+
+	g.output.chunks = append(g.output.chunks, outputChunk{g.w, append([]byte{}, data...)})
+	return len(data), nil
+}
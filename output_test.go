@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewOutput(t *testing.T) {
+	t.Run("When output mode is interleaved.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		output := NewOutput(OutputInterleaved, "foo", 3)
+
+		assert.IsType(&interleavedOutput{}, output)
+		assert.Equal(Stdout, output.Stdout())
+		assert.Equal(Stderr, output.Stderr())
+	})
+
+	t.Run("When output mode is prefixed.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		output := NewOutput(OutputPrefixed, "foo", 5)
+
+		assert.IsType(&prefixedOutput{}, output)
+
+		expected := "foo   | "
+		assert.Equal(expected, output.(*prefixedOutput).stdout.prefix)
+	})
+
+	t.Run("When output mode is grouped.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		output := NewOutput(OutputGrouped, "foo", 3)
+
+		assert.IsType(&groupedOutput{}, output)
+	})
+}
+
+func TestIsValidOutputMode(t *testing.T) {
+	assert := assert2.New(t)
+
+	assert.True(IsValidOutputMode("interleaved"))
+	assert.True(IsValidOutputMode("prefixed"))
+	assert.True(IsValidOutputMode("grouped"))
+	assert.False(IsValidOutputMode("fancy"))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	t.Run("When writing lines in pieces.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := &bytes.Buffer{}
+		writer := &prefixWriter{w: buf, prefix: "foo | "}
+
+		writer.Write([]byte("bar\nba"))
+		writer.Write([]byte("z\nqu"))
+
+		expected := "foo | bar\nfoo | baz\n"
+		assert.Equal(expected, buf.String())
+
+		writer.Flush()
+
+		expected2 := "foo | bar\nfoo | baz\nfoo | qu\n"
+		assert.Equal(expected2, buf.String())
+	})
+}
+
+func TestGroupedOutput(t *testing.T) {
+	t.Run("When writing to stdout and stderr.", func(t *testing.T) {
+		defer HookStdio()
+
+		assert := assert2.New(t)
+
+		outbuf := &bytes.Buffer{}
+		errbuf := &bytes.Buffer{}
+		Stdout = outbuf
+		Stderr = errbuf
+
+		output := &groupedOutput{}
+		output.Stdout().Write([]byte("foo\n"))
+		output.Stderr().Write([]byte("bar\n"))
+		output.Stdout().Write([]byte("baz\n"))
+
+		expected := ""
+		assert.Equal(expected, outbuf.String())
+		assert.Equal(expected, errbuf.String())
+
+		output.Close()
+
+		expected2 := "foo\nbaz\n"
+		assert.Equal(expected2, outbuf.String())
+
+		expected3 := "bar\n"
+		assert.Equal(expected3, errbuf.String())
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Param is a parameter declared by a task, given as a KEY=value argument.
+type Param struct {
+	Name        string
+	Default     string
+	HasDefault  bool
+	Required    bool
+	Enum        []string
+	Description string
+}
+
+// Usage returns the description of the parameter shown in the task listing and the help.
+func (p Param) Usage() string {
+	var notes []string
+	if p.Required {
+		notes = append(notes, "required")
+	}
+	if p.HasDefault {
+		notes = append(notes, fmt.Sprintf("default: %s", p.Default))
+	}
+	if len(p.Enum) > 0 {
+		notes = append(notes, fmt.Sprintf("allowed: %s", strings.Join(p.Enum, ", ")))
+	}
+
+	usage := p.Name
+	if p.Description != "" {
+		usage += "  " + p.Description
+	}
+	if len(notes) > 0 {
+		usage += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return usage
+}
+
+// Resolve returns the value of the parameter from the given value or the default.
+func (p Param) Resolve(value string, given bool) (string, error) {
+	if !given {
+		if p.Required {
+			return "", fmt.Errorf("required parameter is not given. param: %s", p.Name)
+		}
+		value = p.Default
+	}
+
+	if len(p.Enum) > 0 && (given || p.HasDefault) {
+		for _, allowed := range p.Enum {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("invalid value for parameter. param: %s, value: %s, allowed: %s", p.Name, value, strings.Join(p.Enum, ", "))
+	}
+	return value, nil
+}
+
+// ParamError reports that the parameters given to a task are invalid.
+type ParamError struct {
+	Task string
+	err  error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s, task: %s", e.err.Error(), e.Task)
+}
+
+func (e *ParamError) ExitCode() int {
+	return InvalidOption
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParam_Usage(t *testing.T) {
+	t.Run("When the param has only name.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		param := Param{Name: "ENV"}
+
+		expected := "ENV"
+		assert.Equal(expected, param.Usage())
+	})
+
+	t.Run("When the param has all attributes.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		param := Param{
+			Name:        "ENV",
+			Default:     "staging",
+			HasDefault:  true,
+			Required:    true,
+			Enum:        []string{"staging", "production"},
+			Description: "Target environment.",
+		}
+
+		expected := "ENV  Target environment. (required, default: staging, allowed: staging, production)"
+		assert.Equal(expected, param.Usage())
+	})
+}
+
+func TestParam_Resolve(t *testing.T) {
+	param := Param{
+		Name:       "ENV",
+		Default:    "staging",
+		HasDefault: true,
+		Enum:       []string{"staging", "production"},
+	}
+
+	t.Run("When the value is given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := param.Resolve("production", true)
+
+		assert.NoError(err)
+
+		expected := "production"
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the value is not given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := param.Resolve("", false)
+
+		assert.NoError(err)
+
+		expected := "staging"
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the value is not allowed.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := param.Resolve("development", true)
+
+		expected := "invalid value for parameter. param: ENV, value: development, allowed: staging, production"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the required value is not given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := Param{Name: "REGION", Required: true}.Resolve("", false)
+
+		expected := "required parameter is not given. param: REGION"
+		assert.EqualError(err, expected)
+	})
+
+	t.Run("When the optional value without default is not given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := Param{Name: "REGION", Enum: []string{"eu", "us"}}.Resolve("", false)
+
+		assert.NoError(err)
+
+		expected := ""
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestParamError(t *testing.T) {
+	assert := assert2.New(t)
+
+	err := &ParamError{Task: "deploy", err: fmt.Errorf("required parameter is not given. param: REGION")}
+
+	expected := "required parameter is not given. param: REGION, task: deploy"
+	assert.EqualError(err, expected)
+
+	expected2 := InvalidOption
+	assert.Equal(expected2, err.ExitCode())
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// signalProcessGroup kills the process, because Windows does not support signals to process groups.
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	cmd.Process.Kill()
+}
+
+// processGroupExists always reports false, because the processes are not grouped on Windows.
+func processGroupExists(cmd *exec.Cmd) bool {
+	return false
+}
@@ -1,14 +1,23 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
 
 type Runner interface {
-	Run() error
+	Run(context.Context) error
 }
 
 type RunnerImpl struct {
 	Option Option
 	Config Config
+	// specifiedNames holds the names given on the command line by the names of the tasks,
+	// which differ when the tasks are matched by prefix.
+	specifiedNames map[string]string
 }
 
 var NewRunner = func(option Option, config Config) Runner {
@@ -18,48 +27,471 @@ var NewRunner = func(option Option, config Config) Runner {
 	}
 }
 
-func (r *RunnerImpl) Run() error {
-	if !r.Option.HasSpecifiedTasks() {
+func (r *RunnerImpl) Run(ctx context.Context) error {
+	if !r.Option.HasSpecifiedTasks() && len(r.Option.Tags()) == 0 {
 		Error("Task is not specified")
 		return fmt.Errorf("task is not specified")
 	}
 
-	tasks, err := r.specifiedDefinedTasks()
+	specifiedTasks, err := r.specifiedDefinedTasks()
+	if err != nil {
+		return err
+	}
+	specifiedTasks, err = r.selectTasksByTags(specifiedTasks)
 	if err != nil {
 		return err
 	}
 
-	for _, task := range tasks {
-		if err := r.runOnce(task); err != nil {
-			return err
-		}
+	tasks, err := r.resolveDependencies(specifiedTasks)
+	if err != nil {
+		return err
 	}
-	return nil
+	if r.Option.BeDryRun() {
+		Info("Selected tasks: %s", strings.Join(taskNames(specifiedTasks), ", "))
+		Info("Tasks to run: %s", strings.Join(taskNames(tasks), ", "))
+	}
+	r.overrideVariables(tasks)
+	if err := r.applyParams(tasks); err != nil {
+		return err
+	}
+
+	if timeout := r.Option.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err = r.runTasks(ctx, tasks, specifiedTasks)
+	if sig := runningCommands.Signal(); sig != nil {
+		return &InterruptedError{Signal: sig}
+	}
+	return err
 }
 
 func (r *RunnerImpl) specifiedDefinedTasks() ([]DefinedTask, error) {
 	var tasks []DefinedTask
+	r.specifiedNames = map[string]string{}
 	for _, specifiedTask := range r.Option.SpecifiedTasks() {
-		found := false
+		var definedTasks []DefinedTask
+		if isTaskPattern(specifiedTask) {
+			matchedTasks, err := r.matchDefinedTasks(specifiedTask)
+			if err != nil {
+				return nil, err
+			}
+			definedTasks = matchedTasks
+		} else {
+			definedTask, err := r.findSpecifiedTask(specifiedTask)
+			if err != nil {
+				return nil, err
+			}
+			definedTasks = []DefinedTask{definedTask}
+		}
 
+		for _, definedTask := range definedTasks {
+			if _, ok := r.specifiedNames[definedTask.Name()]; ok {
+				continue
+			}
+			r.specifiedNames[definedTask.Name()] = specifiedTask
+			tasks = append(tasks, definedTask)
+		}
+	}
+	return tasks, nil
+}
+
+// isTaskPattern reports whether the specified task is a glob pattern such as test:*.
+// Character classes are not supported, since `[...]` gives the arguments of the task.
+func isTaskPattern(name string) bool {
+	return strings.ContainsAny(name, "*?")
+}
+
+// matchDefinedTasks returns the tasks matching the glob pattern, except hidden tasks.
+func (r *RunnerImpl) matchDefinedTasks(pattern string) ([]DefinedTask, error) {
+	var tasks []DefinedTask
+	for _, definedTask := range r.Config.DefinedTasks() {
+		if definedTask.Hidden() {
+			continue
+		}
+		matched, err := path.Match(pattern, definedTask.Name())
+		if err != nil {
+			Error("Invalid task pattern. pattern: %s", pattern)
+			return nil, err
+		}
+		if matched {
+			tasks = append(tasks, definedTask)
+		}
+	}
+	if len(tasks) == 0 {
+		Warn("No tasks match the pattern. pattern: %s", pattern)
+		return nil, fmt.Errorf("no tasks match the pattern")
+	}
+	return tasks, nil
+}
+
+// selectTasksByTags narrows the specified tasks down to the ones with any of Option.Tags(),
+// or selects them from all tasks if no tasks are specified,
+// and excludes the ones with any of Option.SkippedTags().
+func (r *RunnerImpl) selectTasksByTags(tasks []DefinedTask) ([]DefinedTask, error) {
+	tags := r.Option.Tags()
+	skippedTags := r.Option.SkippedTags()
+	if len(tags) == 0 && len(skippedTags) == 0 {
+		return tasks, nil
+	}
+
+	if !r.Option.HasSpecifiedTasks() {
+		tasks = nil
 		for _, definedTask := range r.Config.DefinedTasks() {
-			if definedTask.Name() == specifiedTask {
+			if !definedTask.Hidden() {
 				tasks = append(tasks, definedTask)
-				found = true
+			}
+		}
+	}
+
+	var selected []DefinedTask
+	for _, task := range tasks {
+		if len(tags) > 0 && !hasAnyTag(task, tags) {
+			continue
+		}
+		if hasAnyTag(task, skippedTags) {
+			Debug("Skip task by tag. task: %s, tags: %s", task.Name(), strings.Join(task.Tags(), ", "))
+			continue
+		}
+		selected = append(selected, task)
+	}
+	if len(selected) == 0 {
+		Warn("No tasks are selected. tags: %s, skipped tags: %s", strings.Join(tags, ", "), strings.Join(skippedTags, ", "))
+		return nil, fmt.Errorf("no tasks are selected")
+	}
+	return selected, nil
+}
+
+func taskNames(tasks []DefinedTask) []string {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, task.Name())
+	}
+	return names
+}
+
+// findSpecifiedTask finds the task by the name, or by the prefix of the name if Option.MatchesPrefix() is true.
+// When the task is not found, similar task names are suggested.
+func (r *RunnerImpl) findSpecifiedTask(name string) (DefinedTask, error) {
+	if definedTask, found := r.findDefinedTask(name); found {
+		return definedTask, nil
+	}
+
+	if r.Option.MatchesPrefix() {
+		var candidates []DefinedTask
+		var names []string
+		for _, definedTask := range r.Config.DefinedTasks() {
+			if !definedTask.Hidden() && strings.HasPrefix(definedTask.Name(), name) {
+				candidates = append(candidates, definedTask)
+				names = append(names, definedTask.Name())
+			}
+		}
+		if len(candidates) == 1 {
+			Debug("Specified task is matched by prefix. task: %s, matched: %s", name, names[0])
+			return candidates[0], nil
+		} else if len(candidates) > 1 {
+			Error("Specified task is ambiguous. task: %s, candidates: %s", name, strings.Join(names, ", "))
+			return nil, fmt.Errorf("specified task is ambiguous")
+		}
+	}
+
+	Warn("Specified task is not defined. task: %s", name)
+	if suggestions := r.suggestTasks(name); len(suggestions) > 0 {
+		Warn("Did you mean? candidates: %s", strings.Join(suggestions, ", "))
+	}
+	return nil, fmt.Errorf("specified task is not defined")
+}
+
+// suggestTasks returns the names of the tasks which are close to the name by edit distance,
+// or start with the name, in order of the distance.
+func (r *RunnerImpl) suggestTasks(name string) []string {
+	threshold := len(name)/3 + 1
+	distances := map[string]int{}
+	var suggestions []string
+	for _, definedTask := range r.Config.DefinedTasks() {
+		taskName := definedTask.Name()
+		if definedTask.Hidden() {
+			continue
+		}
+
+		distance := EditDistance(name, taskName)
+		if i := strings.LastIndex(taskName, ":"); i >= 0 {
+			distance = minInt(distance, EditDistance(name, taskName[i+1:]))
+		}
+		if distance <= threshold || strings.HasPrefix(taskName, name) {
+			distances[taskName] = distance
+			suggestions = append(suggestions, taskName)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i int, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	return suggestions
+}
+
+// taskArgs returns the arguments given to the task on the command line.
+func (r *RunnerImpl) taskArgs(name string) []string {
+	if specifiedName, ok := r.specifiedNames[name]; ok {
+		name = specifiedName
+	}
+	return r.Option.TaskArgsOf(name)
+}
+
+// overrideVariables sets the variables given by the command line to the vars and the env of the tasks,
+// so they take precedence over the ones in the config and the vars can refer to them.
+func (r *RunnerImpl) overrideVariables(tasks []DefinedTask) {
+	for _, variable := range r.Option.Variables() {
+		pair := strings.SplitN(variable, "=", 2)
+		Debug("Override variable: %s", variable)
+		for _, task := range tasks {
+			task.OverrideVar(pair[0], pair[1])
+			task.AddEnv(pair[0], pair[1])
+		}
+	}
+}
+
+// applyParams validates the parameters of the tasks before running any of them,
+// and sets the values of the parameters to the vars, so the vars of the tasks can refer to them.
+// The defaults of the params which are not given are also set to the env,
+// and the env is left as it is for the optional params without defaults.
+func (r *RunnerImpl) applyParams(tasks []DefinedTask) error {
+	variables := map[string]string{}
+	for _, variable := range r.Option.Variables() {
+		pair := strings.SplitN(variable, "=", 2)
+		variables[pair[0]] = pair[1]
+	}
+
+	for _, task := range tasks {
+		for _, param := range task.Params() {
+			given, ok := variables[param.Name]
+			value, err := param.Resolve(given, ok)
+			if err != nil {
+				err := &ParamError{Task: task.Name(), err: err}
+				Error(err.Error())
+				return err
+			}
+			task.OverrideVar(param.Name, value)
+			if !ok && param.HasDefault {
+				task.AddEnv(param.Name, value)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RunnerImpl) findDefinedTask(name string) (DefinedTask, bool) {
+	return findTaskByName(r.Config.DefinedTasks(), name)
+}
+
+// resolveDependencies returns the given tasks and all of their dependencies
+// in topological order, each task appearing at most once.
+func (r *RunnerImpl) resolveDependencies(tasks []DefinedTask) ([]DefinedTask, error) {
+	var resolved []DefinedTask
+	visited := make(map[string]bool)
+
+	var visit func(DefinedTask, []string) error
+	visit = func(task DefinedTask, path []string) error {
+		name := task.Name()
+		for i, visiting := range path {
+			if visiting == name {
+				cycle := append(append([]string{}, path[i:]...), name)
+				Error("Dependency cycle detected. cycle: %s", strings.Join(cycle, " -> "))
+				return fmt.Errorf("dependency cycle detected")
+			}
+		}
+
+		if visited[name] {
+			return nil
+		}
+
+		path = append(path, name)
+		for _, dependency := range task.Dependencies() {
+			dependentTask, found := r.findDefinedTask(dependency)
+			if !found {
+				Warn("Dependent task is not defined. task: %s, dependency: %s", name, dependency)
+				return fmt.Errorf("dependent task is not defined")
+			}
+
+			if err := visit(dependentTask, path); err != nil {
+				return err
+			}
+		}
+
+		visited[name] = true
+		resolved = append(resolved, task)
+		return nil
+	}
+
+	for _, task := range tasks {
+		if err := visit(task, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+type taskStatus int
+
+const (
+	taskPending taskStatus = iota
+	taskRunning
+	taskSucceeded
+	taskFailed
+	taskSkipped
+)
+
+type taskResult struct {
+	task DefinedTask
+	err  error
+}
+
+// runTasks runs tasks ordered by resolveDependencies, up to Option.Jobs() at once.
+// A task is started after all of its dependencies have succeeded.
+// When a task fails, the running tasks are cancelled and no more tasks are started
+// unless Option.KeepsGoing() is true.
+func (r *RunnerImpl) runTasks(ctx context.Context, tasks []DefinedTask, specifiedTasks []DefinedTask) error {
+	jobs := r.Option.Jobs()
+	if jobs < 1 {
+		jobs = 1
+	}
+	keepGoing := r.Option.KeepsGoing()
+	outputMode := r.Option.OutputMode()
+
+	width := 0
+	for _, task := range tasks {
+		if len(task.Name()) > width {
+			width = len(task.Name())
+		}
+	}
+
+	// Running tasks are cancelled when a task fails, unless keepGoing.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	status := make(map[string]taskStatus)
+	results := make(chan taskResult)
+	running := 0
+	stopped := false
+
+	var firstErr error
+	var failedTasks []string
+
+	for {
+		for _, task := range tasks {
+			if stopped || running >= jobs || ctx.Err() != nil {
 				break
 			}
+
+			name := task.Name()
+			if status[name] != taskPending {
+				continue
+			}
+
+			ready, blocked := dependencyState(task, status)
+			if blocked {
+				Warn("Skipped task because its dependency failed. task: %s", name)
+				setTaskStatus(status, task, taskSkipped)
+				continue
+			}
+			if !ready {
+				continue
+			}
+
+			var args []string
+			if containsDefinedTask(specifiedTasks, task) {
+				args = r.taskArgs(name)
+			}
+
+			setTaskStatus(status, task, taskRunning)
+			running++
+			output := NewOutput(outputMode, name, width)
+			go func(task DefinedTask, args []string, output Output) {
+				err := r.runOnce(ctx, task, args, output)
+				output.Close()
+				results <- taskResult{task, err}
+			}(task, args, output)
+		}
+
+		if running == 0 {
+			break
 		}
 
-		if !found {
-			Warn("Specified task is not defined. task: %s", specifiedTask)
-			return nil, fmt.Errorf("specified task is not defined")
+		result := <-results
+		running--
+
+		name := result.task.Name()
+		if result.err == context.Canceled && stopped {
+			Warn("Cancelled task because another task failed. task: %s", name)
+			setTaskStatus(status, result.task, taskSkipped)
+		} else if result.err != nil {
+			setTaskStatus(status, result.task, taskFailed)
+			failedTasks = append(failedTasks, name)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			if !keepGoing {
+				stopped = true
+				cancel()
+			}
+		} else {
+			setTaskStatus(status, result.task, taskSucceeded)
 		}
 	}
-	return tasks, nil
+
+	if stopped {
+		for _, task := range tasks {
+			if status[task.Name()] == taskPending {
+				Warn("Cancelled remaining tasks because a task failed.")
+				break
+			}
+		}
+	}
+
+	if len(failedTasks) > 1 {
+		Error("Failed tasks: %s", strings.Join(failedTasks, ", "))
+	}
+
+	return firstErr
 }
 
-func (r *RunnerImpl) runOnce(task DefinedTask) error {
+// setTaskStatus sets the status of the task by its name and aliases,
+// so dependencies given by aliases are found.
+func setTaskStatus(status map[string]taskStatus, task DefinedTask, s taskStatus) {
+	status[task.Name()] = s
+	for _, alias := range task.Aliases() {
+		status[alias] = s
+	}
+}
+
+// dependencyState reports whether all dependencies of the task have succeeded,
+// and whether any of them has failed or been skipped.
+func dependencyState(task DefinedTask, status map[string]taskStatus) (bool, bool) {
+	ready := true
+	for _, dependency := range task.Dependencies() {
+		switch status[dependency] {
+		case taskSucceeded:
+		case taskFailed, taskSkipped:
+			return false, true
+		default:
+			ready = false
+		}
+	}
+	return ready, false
+}
+
+func (r *RunnerImpl) runOnce(ctx context.Context, task DefinedTask, args []string, output Output) error {
 	dryRun := r.Option.BeDryRun()
-	args := r.Option.TaskArgs()
-	return task.Run(dryRun, args)
+	return task.Run(ctx, dryRun, args, output)
+}
+
+func containsDefinedTask(tasks []DefinedTask, task DefinedTask) bool {
+	for _, t := range tasks {
+		if t == task {
+			return true
+		}
+	}
+	return false
 }
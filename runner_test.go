@@ -1,18 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	assert2 "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type MockRunner struct {
 	mock.Mock
 }
 
-func (m *MockRunner) Run() error {
-	return m.Called().Error(0)
+func (m *MockRunner) Run(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
 }
 
 func TestRunnerImpl_Run(t *testing.T) {
@@ -25,12 +28,13 @@ func TestRunnerImpl_Run(t *testing.T) {
 		}
 
 		option.On("HasSpecifiedTasks").Return(false)
+		option.On("Tags").Return()
 
 		t.Run("And return errors.", func(t *testing.T) {
 			iobuffer.Reset()
 
 			assert := assert2.New(t)
-			actual := runner.Run()
+			actual := runner.Run(context.Background())
 			expected := "task is not specified"
 			assert.Error(actual, expected)
 		})
@@ -39,7 +43,7 @@ func TestRunnerImpl_Run(t *testing.T) {
 			iobuffer.Reset()
 
 			assert := assert2.New(t)
-			runner.Run()
+			runner.Run(context.Background())
 			expected := "[ERROR][15:04:05] Task is not specified\n"
 			assert.Equal(expected, iobuffer.String())
 		})
@@ -57,6 +61,7 @@ func TestRunnerImpl_Run(t *testing.T) {
 
 		option.On("HasSpecifiedTasks").Return(true)
 		option.On("SpecifiedTasks").Return("bar")
+		option.On("MatchesPrefix").Return(false)
 		config.On("DefinedTasks").Return(
 			&DefinedTaskImpl{
 				name:     "foo",
@@ -65,7 +70,7 @@ func TestRunnerImpl_Run(t *testing.T) {
 		)
 
 		assert := assert2.New(t)
-		actual := runner.Run()
+		actual := runner.Run(context.Background())
 		expected := "task is not specified"
 		assert.Error(actual, expected)
 	})
@@ -84,36 +89,260 @@ func TestRunnerImpl_Run(t *testing.T) {
 		option.On("HasSpecifiedTasks").Return(true)
 		option.On("SpecifiedTasks").Return("foo")
 
-		option.On("BeDryRun").Once().Return(true)
-		option.On("BeDryRun").Twice().Return(false)
-		option.On("TaskArgs").Return("foo", "bar")
+		option.On("Jobs").Return(1)
+		option.On("KeepsGoing").Return(false)
+		option.On("OutputMode").Return(OutputInterleaved)
+		option.On("Timeout").Return(time.Duration(0))
+		option.On("Variables").Return()
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		// Once for showing the selected tasks and once for running the task.
+		option.On("BeDryRun").Twice().Return(true)
+		option.On("BeDryRun").Return(false)
+		option.On("TaskArgsOf", mock.Anything).Return("foo", "bar")
 		config.On("DefinedTasks").Return(task, task)
 
 		task.On("Name").Once().Return("bar")
 		task.On("Name").Twice().Return("foo")
-		task.On("Run", true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
-		task.On("Run", false, []string{"foo", "bar"}).Return(nil)
+		task.On("Name").Return("foo")
+		task.On("Dependencies").Return()
+		task.On("Params").Return()
+		task.On("Aliases").Return()
+		task.On("Run", mock.Anything, true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
+		task.On("Run", mock.Anything, false, []string{"foo", "bar"}).Return(nil)
 
 		t.Run("When an error occurred on run tasks.", func(t *testing.T) {
-			task.On("Run", true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
+			task.On("Run", mock.Anything, true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
 
 			assert := assert2.New(t)
-			actual := runner.Run()
+			actual := runner.Run(context.Background())
 			expected := "mock return"
 			assert.Error(actual, expected)
 		})
 
 		t.Run("When no error occurred on run tasks.", func(t *testing.T) {
-			task.On("Run", true, []string{"foo", "bar"}).Return(nil)
-			task.On("Run", false, []string{"foo", "bar"}).Return(nil)
+			task.On("Run", mock.Anything, true, []string{"foo", "bar"}).Return(nil)
+			task.On("Run", mock.Anything, false, []string{"foo", "bar"}).Return(nil)
 
 			assert := assert2.New(t)
-			actual := runner.Run()
+			actual := runner.Run(context.Background())
 			assert.Nil(actual)
 		})
 	})
 }
 
+func TestRunnerImpl_Run_withDependencies(t *testing.T) {
+	t.Run("When specified task has dependencies.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		config := new(MockConfig)
+		build := new(MockDefinedTask)
+		lint := new(MockDefinedTask)
+		runner := RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+
+		option.On("HasSpecifiedTasks").Return(true)
+		option.On("SpecifiedTasks").Return("build")
+		option.On("BeDryRun").Return(false)
+		option.On("TaskArgsOf", mock.Anything).Return("-v")
+		option.On("Jobs").Return(1)
+		option.On("KeepsGoing").Return(false)
+		option.On("OutputMode").Return(OutputInterleaved)
+		option.On("Timeout").Return(time.Duration(0))
+		option.On("Variables").Return()
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		config.On("DefinedTasks").Return(build, lint)
+
+		build.On("Name").Return("build")
+		build.On("Dependencies").Return("lint")
+		build.On("Params").Return()
+		build.On("Aliases").Return()
+		lint.On("Name").Return("lint")
+		lint.On("Dependencies").Return()
+		lint.On("Params").Return()
+		lint.On("Aliases").Return()
+
+		var order []string
+		lint.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			order = append(order, "lint")
+		}).Return(nil)
+		build.On("Run", mock.Anything, false, []string{"-v"}).Run(func(args mock.Arguments) {
+			order = append(order, "build")
+		}).Return(nil)
+
+		actual := runner.Run(context.Background())
+		assert.NoError(actual)
+
+		expected := []string{"lint", "build"}
+		assert.Equal(expected, order)
+	})
+}
+
+func TestRunnerImpl_overrideVariables(t *testing.T) {
+	t.Run("When variables are given.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		runner := RunnerImpl{
+			Option: option,
+		}
+		option.On("Variables").Return("ENV=staging", "URL=https://example.com/?a=b")
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		option.On("BeDryRun").Return(false)
+
+		task := &DefinedTaskImpl{
+			name: "deploy",
+			env:  []string{"ENV=development", "REGION=us"},
+			vars: []Var{{Name: "ENV", Sh: "cat .env-name"}},
+		}
+		runner.overrideVariables([]DefinedTask{task})
+
+		expected := []Var{{Name: "ENV", Value: "staging"}, {Name: "URL", Value: "https://example.com/?a=b"}}
+		assert.Equal(expected, task.overrides)
+
+		expected2 := []string{"ENV=staging", "REGION=us", "URL=https://example.com/?a=b"}
+		assert.Equal(expected2, task.Env())
+
+		expected3 := []Var{{Name: "ENV", Sh: "cat .env-name"}}
+		assert.Equal(expected3, task.Vars())
+	})
+}
+
+func TestRunnerImpl_applyParams(t *testing.T) {
+	newTask := func() *DefinedTaskImpl {
+		return &DefinedTaskImpl{
+			name: "deploy",
+			params: []Param{
+				{Name: "ENV", Default: "staging", HasDefault: true, Enum: []string{"staging", "production"}},
+				{Name: "REGION", Required: true},
+			},
+		}
+	}
+
+	t.Run("When the params are valid.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		runner := RunnerImpl{
+			Option: option,
+		}
+		option.On("Variables").Return("REGION=eu")
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		option.On("BeDryRun").Return(false)
+
+		task := newTask()
+		err := runner.applyParams([]DefinedTask{task})
+
+		assert.NoError(err)
+
+		expected := []Var{{Name: "ENV", Value: "staging"}, {Name: "REGION", Value: "eu"}}
+		assert.Equal(expected, task.overrides)
+
+		expected2 := []string{"ENV=staging"}
+		assert.Equal(expected2, task.Env())
+	})
+
+	t.Run("When an optional param without default is not given.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		runner := RunnerImpl{
+			Option: option,
+		}
+		option.On("Variables").Return()
+
+		task := &DefinedTaskImpl{
+			name:   "deploy",
+			env:    []string{"REGION=eu"},
+			params: []Param{{Name: "REGION"}},
+		}
+		err := runner.applyParams([]DefinedTask{task})
+
+		assert.NoError(err)
+
+		expected := []string{"REGION=eu"}
+		assert.Equal(expected, task.Env())
+	})
+
+	t.Run("When the params are invalid.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		runner := RunnerImpl{
+			Option: option,
+		}
+		option.On("Variables").Return("ENV=development", "REGION=eu")
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		option.On("BeDryRun").Return(false)
+
+		err := runner.applyParams([]DefinedTask{newTask()})
+
+		assert.IsType(&ParamError{}, err)
+
+		expected := "[ERROR][15:04:05] invalid value for parameter. param: ENV, value: development, allowed: staging, production, task: deploy\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
+
+func TestRunnerImpl_Run_withTimeout(t *testing.T) {
+	t.Run("When timeout is specified.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		option := new(MockOption)
+		config := new(MockConfig)
+		task := new(MockDefinedTask)
+		runner := RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+
+		option.On("HasSpecifiedTasks").Return(true)
+		option.On("SpecifiedTasks").Return("test")
+		option.On("BeDryRun").Return(false)
+		option.On("TaskArgsOf", mock.Anything).Return()
+		option.On("Jobs").Return(1)
+		option.On("KeepsGoing").Return(false)
+		option.On("OutputMode").Return(OutputInterleaved)
+		option.On("Timeout").Return(time.Minute)
+		option.On("Variables").Return()
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		config.On("DefinedTasks").Return(task)
+
+		task.On("Name").Return("test")
+		task.On("Dependencies").Return()
+		task.On("Params").Return()
+		task.On("Aliases").Return()
+
+		var deadline time.Time
+		task.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			deadline, _ = args.Get(0).(context.Context).Deadline()
+		}).Return(nil)
+
+		actual := runner.Run(context.Background())
+		assert.NoError(actual)
+
+		assert.True(time.Until(deadline) <= time.Minute)
+		assert.True(time.Until(deadline) > 0)
+	})
+}
+
 func TestRunnerImpl_specifiedDefinedTasks(t *testing.T) {
 	t.Run("Found specified Tasks.", func(t *testing.T) {
 		assert := assert2.New(t)
@@ -163,6 +392,7 @@ func TestRunnerImpl_specifiedDefinedTasks(t *testing.T) {
 		}
 
 		option.On("SpecifiedTasks").Return("pii", "poo")
+		option.On("MatchesPrefix").Return(false)
 		config.On("DefinedTasks").Return(
 			&DefinedTaskImpl{
 				name:     "foo",
@@ -188,6 +418,610 @@ func TestRunnerImpl_specifiedDefinedTasks(t *testing.T) {
 		expected2 := "[WARN][15:04:05] Specified task is not defined. task: pii\n"
 		assert.Equal(expected2, iobuffer.String())
 	})
+
+	newRunner := func(matchesPrefix bool, specifiedTasks ...interface{}) RunnerImpl {
+		option := new(MockOption)
+		option.On("SpecifiedTasks").Return(specifiedTasks...)
+		option.On("MatchesPrefix").Return(matchesPrefix)
+		config := new(MockConfig)
+		config.On("DefinedTasks").Return(
+			&DefinedTaskImpl{name: "build"},
+			&DefinedTaskImpl{name: "build:docker"},
+			&DefinedTaskImpl{name: "bundle"},
+			&DefinedTaskImpl{name: "go:test"},
+			&DefinedTaskImpl{name: "_setup", hidden: true},
+		)
+		return RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+	}
+
+	t.Run("When similar tasks are defined.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner(false, "tset")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.Nil(tasks)
+		assert.Error(err)
+
+		expected := "[WARN][15:04:05] Specified task is not defined. task: tset\n" +
+			"[WARN][15:04:05] Did you mean? candidates: go:test\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the name is a prefix of tasks.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner(false, "bui")
+
+		_, err := runner.specifiedDefinedTasks()
+
+		assert.Error(err)
+
+		expected := "[WARN][15:04:05] Specified task is not defined. task: bui\n" +
+			"[WARN][15:04:05] Did you mean? candidates: build, build:docker\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When the prefix matches a task.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(true, "bun", "go:")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.NoError(err)
+
+		expected := "bundle"
+		assert.Equal(expected, tasks[0].Name())
+		expected2 := "go:test"
+		assert.Equal(expected2, tasks[1].Name())
+
+		expected3 := map[string]string{"bundle": "bun", "go:test": "go:"}
+		assert.Equal(expected3, runner.specifiedNames)
+	})
+
+	t.Run("When the name matches a task exactly and others by prefix.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(true, "build")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.NoError(err)
+
+		expected := "build"
+		assert.Equal(expected, tasks[0].Name())
+	})
+
+	t.Run("When the prefix is ambiguous.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner(true, "bu")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.Nil(tasks)
+
+		expected := "specified task is ambiguous"
+		assert.EqualError(err, expected)
+
+		expected2 := "[ERROR][15:04:05] Specified task is ambiguous. task: bu, candidates: build, build:docker, bundle\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+
+	t.Run("When the prefix matches only hidden tasks.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner(true, "_se")
+
+		_, err := runner.specifiedDefinedTasks()
+
+		expected := "specified task is not defined"
+		assert.EqualError(err, expected)
+	})
+}
+
+func TestRunnerImpl_specifiedDefinedTasks_withPattern(t *testing.T) {
+	newRunner := func(specifiedTasks ...interface{}) RunnerImpl {
+		option := new(MockOption)
+		option.On("SpecifiedTasks").Return(specifiedTasks...)
+		option.On("MatchesPrefix").Return(false)
+		config := new(MockConfig)
+		config.On("DefinedTasks").Return(
+			&DefinedTaskImpl{name: "lint"},
+			&DefinedTaskImpl{name: "test:_setup", hidden: true},
+			&DefinedTaskImpl{name: "test:e2e"},
+			&DefinedTaskImpl{name: "test:unit"},
+		)
+		return RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+	}
+
+	t.Run("When the pattern matches tasks.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner("test:*", "lint", "test:e2e")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.NoError(err)
+
+		expected := []string{"test:e2e", "test:unit", "lint"}
+		assert.Equal(expected, taskNames(tasks))
+
+		expected2 := map[string]string{"test:e2e": "test:*", "test:unit": "test:*", "lint": "lint"}
+		assert.Equal(expected2, runner.specifiedNames)
+	})
+
+	t.Run("When the pattern matches no tasks.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner("build:*")
+
+		tasks, err := runner.specifiedDefinedTasks()
+
+		assert.Nil(tasks)
+
+		expected := "no tasks match the pattern"
+		assert.EqualError(err, expected)
+
+		expected2 := "[WARN][15:04:05] No tasks match the pattern. pattern: build:*\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
+func TestRunnerImpl_selectTasksByTags(t *testing.T) {
+	lint := &DefinedTaskImpl{name: "lint", tags: []string{"ci"}}
+	unit := &DefinedTaskImpl{name: "test:unit", tags: []string{"ci", "test"}}
+	e2e := &DefinedTaskImpl{name: "test:e2e", tags: []string{"test", "slow"}}
+	setup := &DefinedTaskImpl{name: "_setup", tags: []string{"ci"}, hidden: true}
+
+	newRunner := func(hasSpecifiedTasks bool, tags []interface{}, skippedTags []interface{}) RunnerImpl {
+		option := new(MockOption)
+		option.On("HasSpecifiedTasks").Return(hasSpecifiedTasks)
+		option.On("Tags").Return(tags...)
+		option.On("SkippedTags").Return(skippedTags...)
+		config := new(MockConfig)
+		config.On("DefinedTasks").Return(setup, lint, e2e, unit)
+		return RunnerImpl{
+			Option: option,
+			Config: config,
+		}
+	}
+
+	t.Run("When no tags are given.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(true, nil, nil)
+
+		tasks, err := runner.selectTasksByTags([]DefinedTask{e2e})
+
+		assert.NoError(err)
+
+		expected := []DefinedTask{e2e}
+		assert.Equal(expected, tasks)
+	})
+
+	t.Run("When tags are given without tasks.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(false, []interface{}{"ci"}, nil)
+
+		tasks, err := runner.selectTasksByTags(nil)
+
+		assert.NoError(err)
+
+		expected := []string{"lint", "test:unit"}
+		assert.Equal(expected, taskNames(tasks))
+	})
+
+	t.Run("When tags are given with tasks.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(true, []interface{}{"test"}, nil)
+
+		tasks, err := runner.selectTasksByTags([]DefinedTask{lint, unit})
+
+		assert.NoError(err)
+
+		expected := []string{"test:unit"}
+		assert.Equal(expected, taskNames(tasks))
+	})
+
+	t.Run("When skipped tags are given.", func(t *testing.T) {
+		assert := assert2.New(t)
+		runner := newRunner(true, nil, []interface{}{"slow"})
+
+		tasks, err := runner.selectTasksByTags([]DefinedTask{e2e, unit})
+
+		assert.NoError(err)
+
+		expected := []string{"test:unit"}
+		assert.Equal(expected, taskNames(tasks))
+	})
+
+	t.Run("When no tasks are selected.", func(t *testing.T) {
+		iobuffer.Reset()
+		defer iobuffer.Reset()
+
+		assert := assert2.New(t)
+		runner := newRunner(false, []interface{}{"test"}, []interface{}{"test"})
+
+		tasks, err := runner.selectTasksByTags(nil)
+
+		assert.Nil(tasks)
+
+		expected := "no tasks are selected"
+		assert.EqualError(err, expected)
+
+		expected2 := "[DEBUG][15:04:05] Skip task by tag. task: test:e2e, tags: test, slow\n" +
+			"[DEBUG][15:04:05] Skip task by tag. task: test:unit, tags: ci, test\n" +
+			"[WARN][15:04:05] No tasks are selected. tags: test, skipped tags: test\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+}
+
+func TestRunnerImpl_Run_dryRun(t *testing.T) {
+	iobuffer.Reset()
+	defer iobuffer.Reset()
+
+	assert := assert2.New(t)
+
+	option := new(MockOption)
+	config := new(MockConfig)
+	runner := RunnerImpl{
+		Option: option,
+		Config: config,
+	}
+
+	option.On("HasSpecifiedTasks").Return(true)
+	option.On("SpecifiedTasks").Return("test:*")
+	option.On("MatchesPrefix").Return(false)
+	option.On("Tags").Return()
+	option.On("SkippedTags").Return("slow")
+	option.On("BeDryRun").Return(true)
+	option.On("TaskArgsOf", mock.Anything).Return()
+	option.On("Jobs").Return(1)
+	option.On("KeepsGoing").Return(false)
+	option.On("OutputMode").Return(OutputInterleaved)
+	option.On("Timeout").Return(time.Duration(0))
+	option.On("Variables").Return()
+	config.On("DefinedTasks").Return(
+		&DefinedTaskImpl{name: "build"},
+		&DefinedTaskImpl{name: "test:e2e", tags: []string{"slow"}},
+		&DefinedTaskImpl{name: "test:unit", dependencies: []string{"build"}},
+	)
+
+	actual := runner.Run(context.Background())
+	assert.NoError(actual)
+
+	expected := "[INFO][15:04:05] Selected tasks: test:unit\n[INFO][15:04:05] Tasks to run: build, test:unit\n"
+	assert.Contains(iobuffer.String(), expected)
+}
+
+func TestRunnerImpl_Run_withAliases(t *testing.T) {
+	assert := assert2.New(t)
+
+	option := new(MockOption)
+	config := new(MockConfig)
+	runner := RunnerImpl{
+		Option: option,
+		Config: config,
+	}
+
+	option.On("HasSpecifiedTasks").Return(true)
+	option.On("SpecifiedTasks").Return("t")
+	option.On("Tags").Return()
+	option.On("SkippedTags").Return()
+	option.On("BeDryRun").Return(false)
+	option.On("TaskArgsOf", "t").Return("-v")
+	option.On("Jobs").Return(2)
+	option.On("KeepsGoing").Return(false)
+	option.On("OutputMode").Return(OutputInterleaved)
+	option.On("Timeout").Return(time.Duration(0))
+	option.On("Variables").Return()
+
+	build := new(MockDefinedTask)
+	test := new(MockDefinedTask)
+	config.On("DefinedTasks").Return(build, test)
+
+	build.On("Name").Return("build")
+	build.On("Aliases").Return("compile")
+	build.On("Dependencies").Return()
+	build.On("Params").Return()
+	test.On("Name").Return("test")
+	test.On("Aliases").Return("t")
+	test.On("Dependencies").Return("compile")
+	test.On("Params").Return()
+
+	var order []string
+	build.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+		order = append(order, "build")
+	}).Return(nil)
+	test.On("Run", mock.Anything, false, []string{"-v"}).Run(func(args mock.Arguments) {
+		order = append(order, "test")
+	}).Return(nil)
+
+	actual := runner.Run(context.Background())
+	assert.NoError(actual)
+
+	expected := []string{"build", "test"}
+	assert.Equal(expected, order)
+}
+
+func TestRunnerImpl_taskArgs(t *testing.T) {
+	assert := assert2.New(t)
+	option := new(MockOption)
+	option.On("TaskArgsOf", "bui").Return("-v")
+	option.On("TaskArgsOf", "test").Return()
+	runner := RunnerImpl{
+		Option:         option,
+		specifiedNames: map[string]string{"build": "bui"},
+	}
+
+	expected := []string{"-v"}
+	assert.Equal(expected, runner.taskArgs("build"))
+
+	assert.Empty(runner.taskArgs("test"))
+}
+
+func TestRunnerImpl_resolveDependencies(t *testing.T) {
+	newRunner := func(tasks ...interface{}) RunnerImpl {
+		config := new(MockConfig)
+		config.On("DefinedTasks").Return(tasks...)
+		return RunnerImpl{
+			Option: new(MockOption),
+			Config: config,
+		}
+	}
+
+	names := func(tasks []DefinedTask) []string {
+		var ret []string
+		for _, task := range tasks {
+			ret = append(ret, task.Name())
+		}
+		return ret
+	}
+
+	t.Run("When tasks have shared dependencies.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		build := &DefinedTaskImpl{name: "build", dependencies: []string{"lint", "test"}}
+		lint := &DefinedTaskImpl{name: "lint", dependencies: []string{"format"}}
+		test := &DefinedTaskImpl{name: "test", dependencies: []string{"format"}}
+		format := &DefinedTaskImpl{name: "format"}
+		runner := newRunner(build, lint, test, format)
+
+		tasks, err := runner.resolveDependencies([]DefinedTask{build, test})
+		assert.NoError(err)
+
+		expected := []string{"format", "lint", "test", "build"}
+		assert.Equal(expected, names(tasks))
+	})
+
+	t.Run("When dependency is not defined.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		build := &DefinedTaskImpl{name: "build", dependencies: []string{"lint"}}
+		runner := newRunner(build)
+
+		tasks, err := runner.resolveDependencies([]DefinedTask{build})
+
+		assert.Nil(tasks)
+
+		expected := "dependent task is not defined"
+		assert.EqualError(err, expected)
+
+		expected2 := "[WARN][15:04:05] Dependent task is not defined. task: build, dependency: lint\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+
+	t.Run("When dependencies have cycle.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		build := &DefinedTaskImpl{name: "build", dependencies: []string{"lint"}}
+		lint := &DefinedTaskImpl{name: "lint", dependencies: []string{"test"}}
+		test := &DefinedTaskImpl{name: "test", dependencies: []string{"lint"}}
+		runner := newRunner(build, lint, test)
+
+		tasks, err := runner.resolveDependencies([]DefinedTask{build})
+
+		assert.Nil(tasks)
+
+		expected := "dependency cycle detected"
+		assert.EqualError(err, expected)
+
+		expected2 := "[ERROR][15:04:05] Dependency cycle detected. cycle: lint -> test -> lint\n"
+		assert.Equal(expected2, iobuffer.String())
+	})
+
+	t.Run("When task depends on itself.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		build := &DefinedTaskImpl{name: "build", dependencies: []string{"build"}}
+		runner := newRunner(build)
+
+		_, err := runner.resolveDependencies([]DefinedTask{build})
+
+		assert.Error(err)
+
+		expected := "[ERROR][15:04:05] Dependency cycle detected. cycle: build -> build\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+}
+
+func TestRunnerImpl_runTasks(t *testing.T) {
+	newTask := func(name string, dependencies ...interface{}) *MockDefinedTask {
+		task := new(MockDefinedTask)
+		task.On("Name").Return(name)
+		task.On("Dependencies").Return(dependencies...)
+		task.On("Params").Return()
+		task.On("Aliases").Return()
+		return task
+	}
+
+	newRunner := func(jobs int, keepGoing bool) RunnerImpl {
+		option := new(MockOption)
+		option.On("Jobs").Return(jobs)
+		option.On("KeepsGoing").Return(keepGoing)
+		option.On("OutputMode").Return(OutputInterleaved)
+		option.On("Timeout").Return(time.Duration(0))
+		option.On("Variables").Return()
+		option.On("Tags").Return()
+		option.On("SkippedTags").Return()
+		option.On("BeDryRun").Return(false)
+		option.On("TaskArgsOf", mock.Anything).Return()
+		return RunnerImpl{
+			Option: option,
+			Config: new(MockConfig),
+		}
+	}
+
+	t.Run("When jobs is more than 1.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var current, max int32
+		run := func(args mock.Arguments) {
+			n := atomic.AddInt32(&current, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		}
+
+		lint := newTask("lint")
+		test := newTask("test")
+		vet := newTask("vet")
+		lint.On("Run", mock.Anything, false, []string(nil)).Run(run).Return(nil)
+		test.On("Run", mock.Anything, false, []string(nil)).Run(run).Return(nil)
+		vet.On("Run", mock.Anything, false, []string(nil)).Run(run).Return(nil)
+		runner := newRunner(3, false)
+
+		actual := runner.runTasks(context.Background(), []DefinedTask{lint, test, vet}, nil)
+		assert.NoError(actual)
+
+		expected := int32(3)
+		assert.Equal(expected, max)
+	})
+
+	t.Run("When jobs is more than 1 and tasks have dependencies.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		var finished []string
+		var lintFinished, testFinished int32
+		lint := newTask("lint")
+		test := newTask("test")
+		build := newTask("build", "lint", "test")
+		lint.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			time.Sleep(20 * time.Millisecond)
+			atomic.StoreInt32(&lintFinished, 1)
+		}).Return(nil)
+		test.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			atomic.StoreInt32(&testFinished, 1)
+		}).Return(nil)
+		build.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			if atomic.LoadInt32(&lintFinished) == 1 && atomic.LoadInt32(&testFinished) == 1 {
+				finished = append(finished, "build")
+			}
+		}).Return(nil)
+		runner := newRunner(2, false)
+
+		actual := runner.runTasks(context.Background(), []DefinedTask{lint, test, build}, nil)
+		assert.NoError(actual)
+
+		expected := []string{"build"}
+		assert.Equal(expected, finished)
+	})
+
+	t.Run("When a task failed.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		lint := newTask("lint")
+		test := newTask("test")
+		lint.On("Run", mock.Anything, false, []string(nil)).Return(fmt.Errorf("mock return"))
+		runner := newRunner(1, false)
+
+		actual := runner.runTasks(context.Background(), []DefinedTask{lint, test}, nil)
+		assert.EqualError(actual, "mock return")
+
+		test.AssertNotCalled(t, "Run", mock.Anything, false, []string(nil))
+
+		expected := "[WARN][15:04:05] Cancelled remaining tasks because a task failed.\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When a task failed while other tasks are running.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		lint := newTask("lint")
+		test := newTask("test")
+		vet := newTask("vet")
+		lint.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			time.Sleep(20 * time.Millisecond)
+		}).Return(fmt.Errorf("lint failed"))
+		test.On("Run", mock.Anything, false, []string(nil)).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).Return(context.Canceled)
+		runner := newRunner(2, false)
+
+		actual := runner.runTasks(context.Background(), []DefinedTask{lint, test, vet}, nil)
+		assert.EqualError(actual, "lint failed")
+
+		vet.AssertNotCalled(t, "Run", mock.Anything, false, []string(nil))
+
+		expected := "[WARN][15:04:05] Cancelled task because another task failed. task: test\n" +
+			"[WARN][15:04:05] Cancelled remaining tasks because a task failed.\n"
+		assert.Equal(expected, iobuffer.String())
+	})
+
+	t.Run("When a task failed with keep going.", func(t *testing.T) {
+		iobuffer.Reset()
+
+		assert := assert2.New(t)
+
+		lint := newTask("lint")
+		vet := newTask("vet")
+		test := newTask("test", "lint")
+		build := newTask("build", "test")
+		lint.On("Run", mock.Anything, false, []string(nil)).Return(fmt.Errorf("lint failed"))
+		vet.On("Run", mock.Anything, false, []string(nil)).Return(fmt.Errorf("vet failed"))
+		runner := newRunner(1, true)
+
+		actual := runner.runTasks(context.Background(), []DefinedTask{lint, vet, test, build}, nil)
+		assert.EqualError(actual, "lint failed")
+
+		test.AssertNotCalled(t, "Run", mock.Anything, false, []string(nil))
+		build.AssertNotCalled(t, "Run", mock.Anything, false, []string(nil))
+
+		expected := "[WARN][15:04:05] Skipped task because its dependency failed. task: test\n" +
+			"[WARN][15:04:05] Skipped task because its dependency failed. task: build\n" +
+			"[ERROR][15:04:05] Failed tasks: lint, vet\n"
+		assert.Equal(expected, iobuffer.String())
+	})
 }
 
 func TestRunnerImpl_runOnce(t *testing.T) {
@@ -202,10 +1036,9 @@ func TestRunnerImpl_runOnce(t *testing.T) {
 		}
 
 		option.On("BeDryRun").Return(true)
-		option.On("TaskArgs").Return("foo", "bar")
-		task.On("Run", true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
+		task.On("Run", mock.Anything, true, []string{"foo", "bar"}).Return(fmt.Errorf("mock return"))
 
-		actual := runner.runOnce(task)
+		actual := runner.runOnce(context.Background(), task, []string{"foo", "bar"}, &interleavedOutput{})
 		expected := "mock return"
 		assert.Error(actual, expected)
 	})
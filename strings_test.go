@@ -48,3 +48,27 @@ func TestQuoteString(t *testing.T) {
 		assert.Equal(expected, actual)
 	})
 }
+
+func TestEditDistance(t *testing.T) {
+	t.Run("When the strings are the same.", func(t *testing.T) {
+		assert := assert2.New(t)
+		actual := EditDistance("build", "build")
+		expected := 0
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the strings are different.", func(t *testing.T) {
+		assert := assert2.New(t)
+		actual := EditDistance("biuld", "build")
+		expected := 2
+		assert.Equal(expected, actual)
+
+		actual2 := EditDistance("tst", "test")
+		expected2 := 1
+		assert.Equal(expected2, actual2)
+
+		actual3 := EditDistance("", "lint")
+		expected3 := 4
+		assert.Equal(expected3, actual3)
+	})
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigError is a problem found in a config file, with its position.
+type ConfigError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+	} else if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ConfigErrors is the list of problems found in config files.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+var yamlErrorPattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// configValidator checks the structure of a config file strictly,
+// so typos are reported instead of being ignored.
+type configValidator struct {
+	path    string
+	errors  ConfigErrors
+	aliases []taskAlias
+	// hiddenTasks holds the names of the top-level entries starting with an underscore which are valid tasks.
+	hiddenTasks map[string]bool
+}
+
+// taskAlias is an alias of a task, which is checked after all tasks are validated.
+type taskAlias struct {
+	task string
+	node *yaml.Node
+}
+
+// ValidateConfig returns the problems in the config file.
+var ValidateConfig = func(path string, buf string) ConfigErrors {
+	document, errs := unmarshalConfig(path, buf)
+	if errs != nil {
+		return errs
+	}
+	return validateConfig(path, document).errors
+}
+
+// unmarshalConfig parses the config file into the node tree, which is validated and built into tasks.
+func unmarshalConfig(path string, buf string) (*yaml.Node, ConfigErrors) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(buf), &document); err != nil {
+		v := &configValidator{path: path}
+		v.addYAMLError(err)
+		return nil, v.errors
+	}
+	return &document, nil
+}
+
+// validateConfig validates the document, and keeps the hidden tasks found on the way.
+func validateConfig(path string, document *yaml.Node) *configValidator {
+	v := &configValidator{path: path, hiddenTasks: map[string]bool{}}
+	if len(document.Content) == 0 {
+		return v
+	}
+
+	v.validateRoot(document.Content[0])
+	return v
+}
+
+func (v *configValidator) addYAMLError(err error) {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		configErr := &ConfigError{Path: v.path, Message: strings.TrimPrefix(message, "yaml: ")}
+		if match := yamlErrorPattern.FindStringSubmatch(message); match != nil {
+			configErr.Line, _ = strconv.Atoi(match[1])
+			configErr.Message = match[2]
+		}
+		v.errors = append(v.errors, configErr)
+	}
+}
+
+func (v *configValidator) addError(node *yaml.Node, format string, a ...interface{}) {
+	v.errors = append(v.errors, &ConfigError{
+		Path:    v.path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// resolve follows aliases to the anchored nodes.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func isMergeKey(node *yaml.Node) bool {
+	return node.Value == "<<" && node.ShortTag() == "!!merge"
+}
+
+// mapItems returns the keys and values of the mapping node, except merge keys.
+func mapItems(node *yaml.Node) (keys []*yaml.Node, values []*yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if isMergeKey(node.Content[i]) {
+			continue
+		}
+		keys = append(keys, node.Content[i])
+		values = append(values, resolve(node.Content[i+1]))
+	}
+	return keys, values
+}
+
+// mergedMapItems returns the keys and values of the mapping node including the merged maps,
+// whose keys are overridden by the keys of the node and the maps merged earlier.
+func mergedMapItems(node *yaml.Node) (keys []*yaml.Node, values []*yaml.Node) {
+	defined := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		defined[node.Content[i].Value] = true
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+		if !isMergeKey(key) {
+			keys = append(keys, key)
+			values = append(values, value)
+			continue
+		}
+
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, mergedNode := range merged {
+			mergedNode = resolve(mergedNode)
+			if mergedNode.Kind != yaml.MappingNode {
+				continue
+			}
+			mergedKeys, mergedValues := mergedMapItems(mergedNode)
+			for j, mergedKey := range mergedKeys {
+				if defined[mergedKey.Value] {
+					continue
+				}
+				defined[mergedKey.Value] = true
+				keys = append(keys, mergedKey)
+				values = append(values, mergedValues[j])
+			}
+		}
+	}
+	return keys, values
+}
+
+func isString(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str"
+}
+
+func isScalar(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode
+}
+
+func isStringList(node *yaml.Node) bool {
+	if isString(node) {
+		return true
+	}
+	if node.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, child := range node.Content {
+		if !isString(resolve(child)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDuration(node *yaml.Node) bool {
+	if !isString(node) {
+		return false
+	}
+	duration, err := time.ParseDuration(node.Value)
+	return err == nil && duration >= 0
+}
+
+func (v *configValidator) validateRoot(node *yaml.Node) {
+	node = resolve(node)
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "config must be a map of tasks")
+		return
+	}
+
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		name := key.Value
+		value := values[i]
+		switch {
+		case !isSetting(name, value) && strings.HasPrefix(name, "_"):
+			// Hidden entries are often used only as anchors, so they are not reported,
+			// and they are registered as hidden tasks only if they are valid tasks.
+			hidden := &configValidator{path: v.path}
+			hidden.validateTask(name, value)
+			if len(hidden.errors) == 0 {
+				v.hiddenTasks[name] = true
+			}
+		case !isSetting(name, value):
+			v.validateTask(name, value)
+		case name == "include" || name == "_include":
+			v.validateIncludes(value)
+		case name == "env":
+			v.validateEnv(value)
+		case name == "dotenv":
+			if !isStringList(value) {
+				v.addError(value, "dotenv must be a string or a list of strings")
+			}
+		case name == "vars":
+			v.validateVars(value)
+		}
+	}
+
+	v.validateAliases(keys)
+}
+
+// isSetting reports whether the top-level entry is a setting of the file rather than a task.
+// The entries named env, vars, dotenv and include are settings only in the forms of the settings,
+// so the tasks with these names defined before they were reserved keep working.
+func isSetting(name string, node *yaml.Node) bool {
+	switch name {
+	case "env", "vars":
+		return node.Kind == yaml.MappingNode
+	case "dotenv":
+		return node.Kind != yaml.MappingNode
+	case "include", "_include":
+		if node.Kind != yaml.MappingNode {
+			return true
+		}
+		keys, _ := mapItems(node)
+		for _, key := range keys {
+			if key.Value == "file" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateAliases checks the aliases do not collide with the task names or the other aliases.
+func (v *configValidator) validateAliases(keys []*yaml.Node) {
+	names := map[string]bool{}
+	for _, key := range keys {
+		names[key.Value] = true
+	}
+
+	used := map[string]string{}
+	for _, alias := range v.aliases {
+		name := alias.node.Value
+		if names[name] {
+			v.addError(alias.node, "alias collides with a task name. task: %s, alias: %s", alias.task, name)
+		} else if other, ok := used[name]; ok && other != alias.task {
+			v.addError(alias.node, "alias is already used by another task. task: %s, alias: %s, other: %s", alias.task, name, other)
+		} else {
+			used[name] = alias.task
+		}
+	}
+}
+
+func (v *configValidator) validateIncludes(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.validateInclude(node)
+		return
+	}
+	for _, child := range node.Content {
+		v.validateInclude(resolve(child))
+	}
+}
+
+func (v *configValidator) validateInclude(node *yaml.Node) {
+	if isString(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "include must be a file path or a map with file")
+		return
+	}
+
+	hasFile := false
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		switch key.Value {
+		case "file":
+			hasFile = true
+			if !isString(values[i]) {
+				v.addError(values[i], "file of include must be a string")
+			}
+		case "namespace":
+			if !isString(values[i]) {
+				v.addError(values[i], "namespace of include must be a string")
+			}
+		case "optional":
+			if values[i].ShortTag() != "!!bool" {
+				v.addError(values[i], "optional of include must be a boolean")
+			}
+		default:
+			v.addError(key, "unknown key in include: %s", key.Value)
+		}
+	}
+	if !hasFile {
+		v.addError(node, "include must be a file path or a map with file")
+	}
+}
+
+// withContext appends the context such as the task name to the message.
+func withContext(message string, context ...string) string {
+	if len(context) == 0 {
+		return message
+	}
+	return message + ". " + strings.Join(context, ", ")
+}
+
+func (v *configValidator) validateEnv(node *yaml.Node, context ...string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "%s", withContext("env must be a map", context...))
+		return
+	}
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		if !isScalar(values[i]) {
+			v.addError(values[i], "%s", withContext("env must be a map of scalars", append(context, "key: "+key.Value)...))
+		}
+	}
+}
+
+func (v *configValidator) validateVars(node *yaml.Node, context ...string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "%s", withContext("vars must be a map", context...))
+		return
+	}
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		value := values[i]
+		if isScalar(value) {
+			continue
+		}
+		varContext := append(append([]string{}, context...), "var: "+key.Value)
+		if value.Kind != yaml.MappingNode {
+			v.addError(value, "%s", withContext("var must be a scalar or a map with sh", varContext...))
+			continue
+		}
+
+		hasSh := false
+		varKeys, varValues := mapItems(value)
+		for j, varKey := range varKeys {
+			if varKey.Value != "sh" {
+				v.addError(varKey, "%s", withContext("unknown key in var definition: "+varKey.Value, varContext...))
+				continue
+			}
+			hasSh = true
+			if !isString(varValues[j]) || varValues[j].Value == "" {
+				v.addError(varValues[j], "%s", withContext("sh of var must be a command", varContext...))
+			}
+		}
+		if !hasSh {
+			v.addError(value, "%s", withContext("var must be a scalar or a map with sh", varContext...))
+		}
+	}
+}
+
+func (v *configValidator) validateTask(name string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateDefinition(name, node)
+	case yaml.SequenceNode:
+		v.validateCommands(name, node)
+	default:
+		if !isString(node) {
+			v.addError(node, "task must be a command, a list of commands or a map. task: %s", name)
+		}
+	}
+}
+
+func (v *configValidator) validateCommands(name string, node *yaml.Node) {
+	if isString(node) {
+		return
+	}
+	if node.Kind != yaml.SequenceNode {
+		v.addError(node, "cmds must be a command or a list of commands. task: %s", name)
+		return
+	}
+	for _, child := range node.Content {
+		child = resolve(child)
+		switch {
+		case isString(child):
+		case child.Kind == yaml.SequenceNode:
+			v.validateCommands(name, child)
+		case child.Kind == yaml.MappingNode:
+			v.validateCommandDefinition(name, child)
+		default:
+			v.addError(child, "command must be a string or a map with cmd. task: %s", name)
+		}
+	}
+}
+
+func (v *configValidator) validateCommandDefinition(name string, node *yaml.Node) {
+	hasCommand := false
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		switch key.Value {
+		case "cmd":
+			hasCommand = true
+			if !isString(values[i]) {
+				v.addError(values[i], "cmd must be a string. task: %s", name)
+			}
+		case "timeout":
+			if !isDuration(values[i]) {
+				v.addError(values[i], "timeout must be a duration such as 30s or 5m. task: %s", name)
+			}
+		default:
+			v.addError(key, "unknown key in command definition: %s. task: %s", key.Value, name)
+		}
+	}
+	if !hasCommand {
+		v.addError(node, "command must be a string or a map with cmd. task: %s", name)
+	}
+}
+
+func (v *configValidator) validateDefinition(name string, node *yaml.Node) {
+	keys, values := mapItems(node)
+	for i, key := range keys {
+		value := values[i]
+		switch key.Value {
+		case "desc":
+			if !isString(value) {
+				v.addError(value, "desc must be a string. task: %s", name)
+			}
+		case "cmds":
+			v.validateCommands(name, value)
+		case "deps":
+			if !isStringList(value) {
+				v.addError(value, "deps must be a string or a list of strings. task: %s", name)
+			}
+		case "env":
+			v.validateEnv(value, "task: "+name)
+		case "dotenv":
+			if !isStringList(value) {
+				v.addError(value, "dotenv must be a string or a list of strings. task: %s", name)
+			}
+		case "vars":
+			v.validateVars(value, "task: "+name)
+		case "params":
+			v.validateParams(name, value)
+		case "dir":
+			if !isString(value) {
+				v.addError(value, "dir must be a string. task: %s", name)
+			}
+		case "timeout":
+			if !isDuration(value) {
+				v.addError(value, "timeout must be a duration such as 30s or 5m. task: %s", name)
+			}
+		case "aliases":
+			if !isStringList(value) {
+				v.addError(value, "aliases must be a string or a list of strings. task: %s", name)
+			} else if value.Kind == yaml.SequenceNode {
+				for _, child := range value.Content {
+					v.aliases = append(v.aliases, taskAlias{task: name, node: resolve(child)})
+				}
+			} else {
+				v.aliases = append(v.aliases, taskAlias{task: name, node: value})
+			}
+		case "tags":
+			if !isStringList(value) {
+				v.addError(value, "tags must be a string or a list of strings. task: %s", name)
+			}
+		case "internal":
+			if value.ShortTag() != "!!bool" {
+				v.addError(value, "internal must be a boolean. task: %s", name)
+			}
+		default:
+			v.addError(key, "unknown key in task definition: %s. task: %s", key.Value, name)
+		}
+	}
+}
+
+func (v *configValidator) validateParams(name string, node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(node, "params must be a list. task: %s", name)
+		return
+	}
+
+	for _, child := range node.Content {
+		child = resolve(child)
+		if isString(child) {
+			if !variablePattern.MatchString(child.Value + "=") {
+				v.addError(child, "param name must be a variable name. task: %s, param: %s", name, child.Value)
+			}
+			continue
+		}
+		if child.Kind != yaml.MappingNode {
+			v.addError(child, "param must be a name or a map with name. task: %s", name)
+			continue
+		}
+
+		hasName := false
+		keys, values := mapItems(child)
+		for i, key := range keys {
+			value := values[i]
+			switch key.Value {
+			case "name":
+				hasName = true
+				if !isString(value) || !variablePattern.MatchString(value.Value+"=") {
+					v.addError(value, "param name must be a variable name. task: %s, param: %s", name, value.Value)
+				}
+			case "default":
+				if !isScalar(value) {
+					v.addError(value, "default of param must be a scalar. task: %s", name)
+				}
+			case "required":
+				if value.ShortTag() != "!!bool" {
+					v.addError(value, "required of param must be a boolean. task: %s", name)
+				}
+			case "enum":
+				if value.Kind != yaml.SequenceNode {
+					v.addError(value, "enum of param must be a list of scalars. task: %s", name)
+					continue
+				}
+				for _, allowed := range value.Content {
+					if !isScalar(resolve(allowed)) {
+						v.addError(allowed, "enum of param must be a list of scalars. task: %s", name)
+					}
+				}
+			case "desc":
+				if !isString(value) {
+					v.addError(value, "desc of param must be a string. task: %s", name)
+				}
+			default:
+				v.addError(key, "unknown key in param definition: %s. task: %s", key.Value, name)
+			}
+		}
+		if !hasName {
+			v.addError(child, "param must be a name or a map with name. task: %s", name)
+		}
+	}
+}
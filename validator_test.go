@@ -0,0 +1,102 @@
+package main
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Run("When the config is valid.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "include:\n  - shared.yml\n  - file: go.yml\n    namespace: go\n    optional: true\n" +
+			"env:\n  CGO_ENABLED: 0\ndotenv: [.env]\n" +
+			"vars:\n  VERSION: 1.0.0\n  GIT_SHA:\n    sh: git rev-parse HEAD\n" +
+			"_prepare: &prepare\n  - echo prepare\n" +
+			"test: &test go test\n" +
+			"build:\n  desc: Build.\n  deps: test\n  dir: ./src\n  timeout: 5m\n  internal: false\n  tags: [ci]\n  aliases: [b, compile]\n" +
+			"  env:\n    GOOS: linux\n  vars:\n    NAME: app\n" +
+			"  params:\n    - ENV\n    - name: REGION\n      default: eu\n      required: false\n      enum: [eu, us]\n      desc: Region.\n" +
+			"  cmds:\n    - *prepare\n    - *test\n    - cmd: go build\n      timeout: 1m\n"
+
+		actual := ValidateConfig("taskal.yml", buf)
+
+		assert.Empty(actual)
+	})
+
+	t.Run("When the config is empty.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := ValidateConfig("taskal.yml", "")
+
+		assert.Empty(actual)
+	})
+
+	t.Run("When the config has invalid syntax.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "build: echo build\n  test: go test\n"
+		actual := ValidateConfig("taskal.yml", buf)
+
+		expected := "taskal.yml:2: mapping values are not allowed in this context"
+		assert.EqualError(actual, expected)
+	})
+
+	t.Run("When the config has problems.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		buf := "empty:\n" +
+			"number: 1\n" +
+			"build:\n  desc: [Build]\n  command: go build\n  cmds:\n    - 1\n    - timeout: 5m\n" +
+			"_hidden: [1, 2]\n" +
+			"include:\n  - file: shared.yml\n    optional: yes please\n" +
+			"setup:\n  internal: 1\n  tags: {ci: true}\n" +
+			"lint:\n  aliases: [build, l]\n  cmds: golint\n" +
+			"vet:\n  aliases: l\n  cmds: go vet\n" +
+			"fmt:\n  aliases: {f: true}\n  cmds: gofmt\n"
+		actual := ValidateConfig("taskal.yml", buf)
+
+		expected := []string{
+			"taskal.yml:1:7: task must be a command, a list of commands or a map. task: empty",
+			"taskal.yml:2:9: task must be a command, a list of commands or a map. task: number",
+			"taskal.yml:4:9: desc must be a string. task: build",
+			"taskal.yml:5:3: unknown key in task definition: command. task: build",
+			"taskal.yml:7:7: command must be a string or a map with cmd. task: build",
+			"taskal.yml:8:7: command must be a string or a map with cmd. task: build",
+			"taskal.yml:12:15: optional of include must be a boolean",
+			"taskal.yml:14:13: internal must be a boolean. task: setup",
+			"taskal.yml:15:9: tags must be a string or a list of strings. task: setup",
+			"taskal.yml:23:12: aliases must be a string or a list of strings. task: fmt",
+			"taskal.yml:17:13: alias collides with a task name. task: lint, alias: build",
+			"taskal.yml:20:12: alias is already used by another task. task: vet, alias: l, other: lint",
+		}
+		var messages []string
+		for _, err := range actual {
+			messages = append(messages, err.Error())
+		}
+		assert.Equal(expected, messages)
+	})
+}
+
+func TestValidateConfig_hiddenTasks(t *testing.T) {
+	assert := assert2.New(t)
+
+	buf := "_include: shared.yml\n_test: go test\n_env:\n  FOO: foo\n_build:\n  internal: true\n  cmds: go build\nlint: golint\n"
+	document, errs := unmarshalConfig("taskal.yml", buf)
+	assert.Nil(errs)
+
+	actual := validateConfig("taskal.yml", document)
+
+	assert.Empty(actual.errors)
+
+	expected := map[string]bool{"_test": true, "_build": true}
+	assert.Equal(expected, actual.hiddenTasks)
+}
+
+func TestConfigError(t *testing.T) {
+	assert := assert2.New(t)
+
+	assert.EqualError(&ConfigError{Path: "taskal.yml", Line: 2, Column: 3, Message: "invalid"}, "taskal.yml:2:3: invalid")
+	assert.EqualError(&ConfigError{Path: "taskal.yml", Line: 2, Message: "invalid"}, "taskal.yml:2: invalid")
+	assert.EqualError(&ConfigError{Path: "taskal.yml", Message: "invalid"}, "taskal.yml: invalid")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/fatih/color"
+	"io"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Var is a template variable of a task.
+// When Sh is set, the value is the output of the shell command.
+type Var struct {
+	Name  string
+	Value string
+	Sh    string
+}
+
+func setVar(vars []Var, v Var) []Var {
+	for i := range vars {
+		if vars[i].Name == v.Name {
+			vars[i] = v
+			return vars
+		}
+	}
+	return append(vars, v)
+}
+
+// builtinVars returns the variables available in all tasks.
+func builtinVars(taskName string, rootDir string, args []string) map[string]interface{} {
+	return map[string]interface{}{
+		"TASK":     taskName,
+		"ROOT_DIR": rootDir,
+		"CLI_ARGS": strings.Join(args, " "),
+		"OS":       runtime.GOOS,
+		"ARCH":     runtime.GOARCH,
+	}
+}
+
+// renderTemplate renders the text with text/template.
+// Referring to an undefined variable is an error.
+func renderTemplate(name string, text string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// refersToVar reports whether any of the texts may refer to the variable.
+func refersToVar(texts []string, name string) bool {
+	pattern := regexp.MustCompile(`\.` + regexp.QuoteMeta(name) + `\b`)
+	for _, text := range texts {
+		if strings.Contains(text, "{{") && pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// shVarCache holds the outputs of the commands of sh vars, so each command is executed once per invocation.
+type shVarCache struct {
+	mutex   sync.Mutex
+	results map[string]*shVarResult
+}
+
+type shVarResult struct {
+	once  sync.Once
+	value string
+	err   error
+}
+
+var shVars = newShVarCache()
+
+func newShVarCache() *shVarCache {
+	return &shVarCache{
+		results: map[string]*shVarResult{},
+	}
+}
+
+// Evaluate executes the command in dir and returns its output without trailing newlines.
+// The output of the same command in the same directory with the same env is reused.
+// In a dry run, the command is not executed and the value is the command in $(...).
+func (c *shVarCache) Evaluate(ctx context.Context, dryRun bool, command string, dir string, env []string, output Output) (string, error) {
+	if dryRun {
+		InfoTo(output, color.HiBlackString("Evaluate var. sh: %s", QuoteString(command)))
+		return "$(" + command + ")", nil
+	}
+
+	key := strings.Join(append([]string{dir, command}, env...), "\x00")
+	c.mutex.Lock()
+	result, ok := c.results[key]
+	if !ok {
+		result = &shVarResult{}
+		c.results[key] = result
+	}
+	c.mutex.Unlock()
+
+	result.once.Do(func() {
+		InfoTo(output, color.HiBlackString("Evaluate var. sh: %s", QuoteString(command)))
+		capture := &captureOutput{output: output}
+		if err := NewExecutor(false, command, nil, dir, env, capture, 0).Execute(ctx); err != nil {
+			result.err = err
+			return
+		}
+		result.value = strings.TrimRight(capture.stdout.String(), "\r\n")
+	})
+	return result.value, result.err
+}
+
+// captureOutput is the Output which keeps the standard output of commands,
+// and writes the standard error to the output of the task.
+// The commands are logged by Evaluate instead of the executor.
+type captureOutput struct {
+	stdout bytes.Buffer
+	output Output
+}
+
+func (o *captureOutput) Stdout() io.Writer {
+	return &o.stdout
+}
+
+func (o *captureOutput) Stderr() io.Writer {
+	return o.output.Stderr()
+}
+
+func (o *captureOutput) Close() {
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSetVar(t *testing.T) {
+	t.Run("When the name is new.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := setVar([]Var{{Name: "FOO", Value: "foo"}}, Var{Name: "BAR", Value: "bar"})
+
+		expected := []Var{{Name: "FOO", Value: "foo"}, {Name: "BAR", Value: "bar"}}
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the name exists.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual := setVar([]Var{{Name: "FOO", Value: "foo"}, {Name: "BAR", Value: "bar"}}, Var{Name: "FOO", Sh: "echo baz"})
+
+		expected := []Var{{Name: "FOO", Sh: "echo baz"}, {Name: "BAR", Value: "bar"}}
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := map[string]interface{}{"VERSION": "1.0.0"}
+
+	t.Run("When the text refers to a variable.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := renderTemplate("build", "echo {{.VERSION}}", data)
+
+		assert.NoError(err)
+
+		expected := "echo 1.0.0"
+		assert.Equal(expected, actual)
+	})
+
+	t.Run("When the text refers to an undefined variable.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := renderTemplate("build", "echo {{.REVISION}}", data)
+
+		assert.Error(err)
+	})
+
+	t.Run("When the text is invalid template.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		_, err := renderTemplate("build", "echo {{.VERSION", data)
+
+		assert.Error(err)
+	})
+
+	t.Run("When the text escapes braces.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		actual, err := renderTemplate("build", `docker inspect -f '{{"{{"}}.Id}}'`, data)
+
+		assert.NoError(err)
+
+		expected := "docker inspect -f '{{.Id}}'"
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestRefersToVar(t *testing.T) {
+	assert := assert2.New(t)
+
+	texts := []string{"echo GIT_SHA", "echo {{.GIT_SHA_SHORT}}", "echo {{.VERSION}}"}
+
+	assert.False(refersToVar(texts, "GIT_SHA"))
+	assert.True(refersToVar(texts, "GIT_SHA_SHORT"))
+	assert.True(refersToVar(texts, "VERSION"))
+}
+
+func TestShVarCache_Evaluate(t *testing.T) {
+	originNewExecutor := NewExecutor
+	defer func() {
+		NewExecutor = originNewExecutor
+	}()
+
+	var commands []string
+	NewExecutor = func(dryRun bool, command string, args []string, dir string, env []string, output Output, timeout time.Duration) Executor {
+		commands = append(commands, dir+": "+command)
+		return executorFunc(func(ctx context.Context) error {
+			if command == "false" {
+				return fmt.Errorf("exit status 1")
+			}
+			fmt.Fprintf(output.Stdout(), "%s\n", command)
+			return nil
+		})
+	}
+
+	t.Run("When the same command is evaluated twice.", func(t *testing.T) {
+		assert := assert2.New(t)
+		commands = nil
+
+		cache := newShVarCache()
+		actual, err := cache.Evaluate(context.Background(), false, "echo foo", "/path/to/project", nil, &interleavedOutput{})
+		assert.NoError(err)
+		actual2, err := cache.Evaluate(context.Background(), false, "echo foo", "/path/to/project", nil, &interleavedOutput{})
+		assert.NoError(err)
+		_, err = cache.Evaluate(context.Background(), false, "echo foo", "/path/to/other", nil, &interleavedOutput{})
+		assert.NoError(err)
+
+		expected := "echo foo"
+		assert.Equal(expected, actual)
+		assert.Equal(expected, actual2)
+
+		expected2 := []string{"/path/to/project: echo foo", "/path/to/other: echo foo"}
+		assert.Equal(expected2, commands)
+	})
+
+	t.Run("When the same command is evaluated with different env.", func(t *testing.T) {
+		assert := assert2.New(t)
+		commands = nil
+
+		cache := newShVarCache()
+		_, err := cache.Evaluate(context.Background(), false, "echo $GOOS", "/path/to/project", []string{"GOOS=linux"}, &interleavedOutput{})
+		assert.NoError(err)
+		_, err = cache.Evaluate(context.Background(), false, "echo $GOOS", "/path/to/project", []string{"GOOS=darwin"}, &interleavedOutput{})
+		assert.NoError(err)
+		_, err = cache.Evaluate(context.Background(), false, "echo $GOOS", "/path/to/project", []string{"GOOS=linux"}, &interleavedOutput{})
+		assert.NoError(err)
+
+		expected := []string{"/path/to/project: echo $GOOS", "/path/to/project: echo $GOOS"}
+		assert.Equal(expected, commands)
+	})
+
+	t.Run("When the command fails.", func(t *testing.T) {
+		assert := assert2.New(t)
+
+		cache := newShVarCache()
+		_, err := cache.Evaluate(context.Background(), false, "false", "/path/to/project", nil, &interleavedOutput{})
+
+		assert.EqualError(err, "exit status 1")
+	})
+}